@@ -0,0 +1,124 @@
+package app
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"httpframwork/app/middleware"
+	"httpframwork/modules/config"
+	"httpframwork/modules/constant"
+	"httpframwork/modules/errorcache"
+	"httpframwork/modules/metrics"
+)
+
+// routeInfo - one entry of the route table served on the admin endpoint
+type routeInfo struct {
+	Name    string   `json:"name"`
+	Path    string   `json:"path"`
+	Methods []string `json:"methods"`
+	Prefix  bool     `json:"prefix,omitempty"`
+}
+
+var publishRuntime sync.Once
+
+// adminHandler - builds the router of the admin endpoint, separate from the public one.
+// Modules add their own diagnostics to AdminRoutes while they are initialized.
+func (a *Application) adminHandler() http.Handler {
+	publishRuntime.Do(func() {
+		expvar.Publish("goroutines", expvar.Func(func() interface{} { return runtime.NumGoroutine() }))
+		expvar.Publish("cpus", expvar.Func(func() interface{} { return runtime.NumCPU() }))
+	})
+
+	router := mux.NewRouter()
+
+	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	router.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+	router.Handle("/debug/vars", expvar.Handler())
+
+	router.HandleFunc("/routes", a.adminRoutes).Methods(http.MethodGet)
+	router.HandleFunc("/config", a.adminConfig).Methods(http.MethodGet)
+	router.HandleFunc("/errors", a.adminErrors).Methods(http.MethodGet)
+	router.HandleFunc("/log-level", a.adminLogLevel).Methods(http.MethodGet, http.MethodPut)
+
+	if a.Config.GetBool(constant.MetricsEnabled) {
+		router.Handle(a.Config.GetString(constant.MetricsPath), metrics.GetInstance(a.Container).Handler()).
+			Methods(http.MethodGet)
+	}
+
+	for _, r := range a.AdminRoutes {
+		router.
+			Path(r.Path).
+			Name(r.Name).
+			HandlerFunc(r.Handler).
+			Methods(r.Method...)
+	}
+
+	router.Use(middleware.AdminToken(func() string {
+		return a.CurrentConfig().GetString(constant.AdminToken)
+	}))
+
+	return router
+}
+
+// adminRoutes - lists the public routes
+func (a *Application) adminRoutes(w http.ResponseWriter, r *http.Request) {
+	table := a.RouteTable()
+	routes := make([]routeInfo, 0, len(table))
+	for _, rt := range table {
+		routes = append(routes, routeInfo{Name: rt.Name, Path: rt.Path, Methods: rt.Method, Prefix: rt.Prefix})
+	}
+
+	writeJSON(w, http.StatusOK, routes)
+}
+
+// adminConfig - returns the effective config with the secret values replaced
+func (a *Application) adminConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, config.GetInstance(a.Container).Redacted())
+}
+
+// adminErrors - returns the error code catalog
+func (a *Application) adminErrors(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, errorcache.GetInstance(a.Container).GetAll())
+}
+
+// adminLogLevel - returns or switches the log level until the next restart or change of app.log_level
+func (a *Application) adminLogLevel(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Level string `json:"level"`
+	}
+
+	if r.Method == http.MethodPut {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		level, err := logrus.ParseLevel(body.Level)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		a.Log.SetLevel(level)
+		a.Log.Infof("log level changed to %s from the admin endpoint", level)
+	}
+
+	body.Level = a.Log.GetLevel().String()
+	writeJSON(w, http.StatusOK, body)
+}
+
+// writeJSON - writes res as the JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, res interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
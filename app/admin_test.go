@@ -0,0 +1,54 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsListener(t *testing.T) {
+	tests := []struct {
+		name   string
+		yml    string
+		public int
+		admin  int
+	}{
+		{"admin only by default", "", http.StatusNotFound, http.StatusOK},
+		{"public when asked", "  metrics:\n    public: true\n", http.StatusOK, http.StatusOK},
+		{"disabled", "  metrics:\n    enabled: false\n    public: true\n", http.StatusNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t, "  admin:\n    token: secret\n"+tt.yml)
+
+			rec := httptest.NewRecorder()
+			a.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			if rec.Code != tt.public {
+				t.Errorf("public listener: expected %d, got %d", tt.public, rec.Code)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			rec = httptest.NewRecorder()
+			a.adminHandler().ServeHTTP(rec, req)
+			if rec.Code != tt.admin {
+				t.Errorf("admin listener: expected %d, got %d", tt.admin, rec.Code)
+			}
+			if tt.admin != http.StatusOK {
+				return
+			}
+			if !strings.Contains(rec.Body.String(), "# TYPE") {
+				t.Errorf("expected the prometheus text format, got\n%s", rec.Body.String())
+			}
+
+			// the admin token guards the metrics like every other diagnostic
+			rec = httptest.NewRecorder()
+			a.adminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("expected 401 without the admin token, got %d", rec.Code)
+			}
+		})
+	}
+}
@@ -13,9 +13,12 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/spf13/viper"
+	"httpframwork/modules/config"
 	"httpframwork/modules/constant"
 	"httpframwork/modules/container"
+	"httpframwork/modules/errorcache"
 	"httpframwork/modules/logger"
+	"httpframwork/modules/tracing"
 )
 
 type Api struct {
@@ -28,6 +31,14 @@ type Api struct {
 	Vars      map[string]string
 	Log       *logs.Log
 	Status    int
+	Peer      *Peer
+	Span      *tracing.Span
+}
+
+// ErrorResponse is the body written by ResponseError
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"msg"`
 }
 
 func (api *Api) GetHandler(name string, path string, methods []string, handler func()) (string, string, []string, http.HandlerFunc) {
@@ -43,7 +54,11 @@ func (api *Api) GetHandler(name string, path string, methods []string, handler f
 
 // Calls other init method to initialize resources
 func (api *Api) Init() {
+	// follow config reloads
+	api.Config = config.GetInstance(api.Container).Current()
 	api.Vars = mux.Vars(api.Request)
+	api.Peer = PeerIdentity(api.Request)
+	api.Span = tracing.SpanFromContext(api.Request.Context())
 	api.initLogger()
 }
 
@@ -52,9 +67,16 @@ func (api *Api) initLogger() {
 	// obtain log folder form config
 	lPath := api.Config.GetString(constant.AppLogFolder)
 	api.Log = logs.New(lPath)
+	// correlate the log with the trace of the request
+	if traceID := api.Span.TraceID(); traceID != "" {
+		api.Log.SetIdentify(traceID)
+	}
 
 	api.Log.Print("Start ", time.Now().UTC().Format(constant.DefaultDateTimeFormat))
 	api.Log.Print("IP ", api.GetClientIP())
+	if api.Peer != nil {
+		api.Log.Print("Peer ", api.Peer.Subject)
+	}
 	api.Log.Print("Resource ", api.Name)
 	api.Log.Print("Method ", api.Request.Method)
 	api.Log.Print("URL ", api.Request.URL.String())
@@ -62,6 +84,12 @@ func (api *Api) initLogger() {
 	api.Log.Print("Request ", strings.Replace(string(api.Body()), "\n", "", -1))
 }
 
+// StartSpan - starts a child span of the request span, call Finish on it when the operation is done.
+// The span is nil when tracing is disabled, its methods are safe to call anyway.
+func (api *Api) StartSpan(name string) *tracing.Span {
+	return api.Span.StartChild(name)
+}
+
 // ResponseJSON
 func (api *Api) ResponseJSON(res interface{}) {
 	b, _ := json.Marshal(res)
@@ -71,6 +99,30 @@ func (api *Api) ResponseJSON(res interface{}) {
 	return
 }
 
+// writeJSON - writes res as JSON with api.Status, for the handlers answering with another status than 200
+func (api *Api) writeJSON(res interface{}) {
+	b, _ := json.Marshal(res)
+	api.Response.Header().Set("Content-Type", "application/json")
+	api.Response.WriteHeader(api.Status)
+	api.Response.Write(b)
+}
+
+// ResponseError - writes the error registered under code in errorcache with its status,
+// args fill the placeholders of the message
+func (api *Api) ResponseError(code string, args ...interface{}) {
+	e := errorcache.GetInstance(api.Container).GetError(code)
+	if e.Status == 0 {
+		e.Status, e.Message = http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+	}
+	if len(args) > 0 {
+		e.Message = fmt.Sprintf(e.Message, args...)
+	}
+
+	api.Status = e.Status
+	api.RawBody = ErrorResponse{Code: code, Message: e.Message}
+	api.writeJSON(api.RawBody)
+}
+
 // ResponseTest
 func (api *Api) ResponseText(res interface{}) {
 	api.Response.WriteHeader(http.StatusOK)
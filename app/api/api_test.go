@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	api := &Api{Response: rec, Status: http.StatusCreated}
+
+	// ResponseJSON always answers 200, the status is only logged
+	api.ResponseJSON(map[string]int{"n": 1})
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"n":1}` {
+		t.Errorf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	api := &Api{Response: rec, Status: http.StatusServiceUnavailable}
+
+	api.writeJSON(map[string]string{"status": "fail"})
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != `{"status":"fail"}` {
+		t.Errorf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected the JSON content type, got %q", got)
+	}
+}
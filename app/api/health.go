@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/spf13/viper"
+	"httpframwork/modules/container"
+	"httpframwork/modules/health"
+)
+
+type Health struct {
+	Api
+	ready bool
+}
+
+// Registers the liveness probe with the router
+func RegisterLivez(cont *container.Container, conf *viper.Viper) (string, string, []string, http.HandlerFunc) {
+	h := &Health{
+		Api: Api{
+			Container: cont,
+			Config:    conf,
+		},
+	}
+
+	return h.GetHandler("livez", "/livez", []string{http.MethodGet}, h.handler)
+}
+
+// Registers the readiness probe with the router
+func RegisterReadyz(cont *container.Container, conf *viper.Viper) (string, string, []string, http.HandlerFunc) {
+	h := &Health{
+		Api: Api{
+			Container: cont,
+			Config:    conf,
+		},
+		ready: true,
+	}
+
+	return h.GetHandler("readyz", "/readyz", []string{http.MethodGet}, h.handler)
+}
+
+// Runs the probe checks and reports every result, failing probes answer 503
+func (h *Health) handler() {
+	registry := health.GetInstance(h.Container)
+
+	var report health.Report
+	if h.ready {
+		report = registry.Ready(h.Request.Context())
+	} else {
+		report = registry.Live(h.Request.Context())
+	}
+
+	h.Status = http.StatusOK
+	if !report.Healthy() {
+		h.Status = http.StatusServiceUnavailable
+	}
+
+	h.ResponseHeaders(map[string]string{"Cache-Control": "no-store"})
+	h.writeJSON(report)
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/spf13/viper"
 	"httpframwork/modules/container"
+	"httpframwork/modules/health"
+	"httpframwork/modules/version"
 )
 
 type Heartbeat struct {
@@ -23,13 +25,19 @@ func RegisterHeartbeat(cont *container.Container, conf *viper.Viper) (string, st
 	return h.GetHandler("heartbeat", "/heartbeat", []string{http.MethodGet}, h.handler)
 }
 
-// Perform the logic here
+// Perform the logic here, the heartbeat follows the readiness checks
 func (h *Heartbeat) handler() {
 	res := map[string]interface{}{
 		"Status":  1,
 		"Message": "success",
+		"Build":   version.Get(),
 	}
 
 	h.Status = http.StatusOK
-	h.ResponseJSON(res)
+	if !health.GetInstance(h.Container).Ready(h.Request.Context()).Healthy() {
+		res["Status"], res["Message"] = 0, "unavailable"
+		h.Status = http.StatusServiceUnavailable
+	}
+
+	h.writeJSON(res)
 }
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"crypto/x509"
+	"net/http"
+)
+
+// Peer holds the identity of a client that presented a verified TLS certificate
+type Peer struct {
+	Subject        string
+	CommonName     string
+	DNSNames       []string
+	EmailAddresses []string
+	IPAddresses    []string
+	URIs           []string
+	Certificate    *x509.Certificate
+}
+
+// PeerIdentity returns the verified client certificate identity of the request,
+// nil when the client did not present a certificate or it was not verified
+func PeerIdentity(r *http.Request) *Peer {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+	peer := &Peer{
+		Subject:        cert.Subject.String(),
+		CommonName:     cert.Subject.CommonName,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		Certificate:    cert,
+	}
+
+	for _, ip := range cert.IPAddresses {
+		peer.IPAddresses = append(peer.IPAddresses, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		peer.URIs = append(peer.URIs, uri.String())
+	}
+
+	return peer
+}
+
+// SANs returns all the subject alternative names of the peer
+func (p *Peer) SANs() []string {
+	sans := make([]string, 0, len(p.DNSNames)+len(p.EmailAddresses)+len(p.IPAddresses)+len(p.URIs))
+	sans = append(sans, p.DNSNames...)
+	sans = append(sans, p.EmailAddresses...)
+	sans = append(sans, p.IPAddresses...)
+	sans = append(sans, p.URIs...)
+
+	return sans
+}
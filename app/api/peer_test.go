@@ -0,0 +1,49 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestPeerIdentity(t *testing.T) {
+	uri, _ := url.Parse("spiffe://test/client")
+	cert := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "client.test", Organization: []string{"test"}},
+		DNSNames:       []string{"client.test"},
+		EmailAddresses: []string{"ops@client.test"},
+		IPAddresses:    []net.IP{net.IPv4(10, 0, 0, 1)},
+		URIs:           []*url.URL{uri},
+	}
+
+	r := httptest.NewRequest("GET", "https://app.test/", nil)
+	if peer := PeerIdentity(r); peer != nil {
+		t.Errorf("expected no peer without verified chains, got %+v", peer)
+	}
+
+	// a presented certificate that was not verified is not an identity
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	if peer := PeerIdentity(r); peer != nil {
+		t.Errorf("expected no peer for an unverified certificate, got %+v", peer)
+	}
+
+	r.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+	peer := PeerIdentity(r)
+	if peer == nil || peer.Subject != "CN=client.test,O=test" || peer.CommonName != "client.test" || peer.Certificate != cert {
+		t.Fatalf("unexpected peer %+v", peer)
+	}
+	want := []string{"client.test", "ops@client.test", "10.0.0.1", "spiffe://test/client"}
+	if !reflect.DeepEqual(peer.SANs(), want) {
+		t.Errorf("expected %v, got %v", want, peer.SANs())
+	}
+
+	// plain requests never carry a peer
+	if peer := PeerIdentity(httptest.NewRequest("GET", "http://app.test/", nil)); peer != nil {
+		t.Errorf("expected no peer over plain http, got %+v", peer)
+	}
+}
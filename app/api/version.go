@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/spf13/viper"
+	"httpframwork/modules/container"
+	"httpframwork/modules/version"
+)
+
+type Version struct {
+	Api
+}
+
+// Registers handle function with the router
+func RegisterVersion(cont *container.Container, conf *viper.Viper) (string, string, []string, http.HandlerFunc) {
+	h := &Version{
+		Api{
+			Container: cont,
+			Config:    conf,
+		},
+	}
+
+	return h.GetHandler("version", "/version", []string{http.MethodGet}, h.handler)
+}
+
+// Reports the running build
+func (h *Version) handler() {
+	h.Status = http.StatusOK
+	h.ResponseJSON(version.Get())
+}
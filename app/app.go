@@ -1,36 +1,82 @@
 package app
 
 import (
-	"crypto/tls"
-	"errors"
+	"context"
 	"fmt"
 	"log"
-	"net"
 	"net/http"
-	"net/url"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"httpframwork/modules/certificate"
+	"httpframwork/modules/config"
+	"httpframwork/modules/connlimit"
 	"httpframwork/modules/constant"
 	"httpframwork/modules/container"
 	"httpframwork/modules/errorcache"
+	"httpframwork/modules/features"
+	"httpframwork/modules/health"
+	"httpframwork/modules/logger"
+	"httpframwork/modules/metrics"
+	"httpframwork/modules/ratelimit"
+	"httpframwork/modules/scheduler"
+	"httpframwork/modules/tracing"
+	"httpframwork/modules/upgrade"
+	"httpframwork/modules/version"
 )
 
+// Options - replaces what New reads from the environment, mostly useful in tests
+type Options struct {
+	// Config is used instead of the config files and environment variables, keys are nested maps
+	Config map[string]interface{}
+	// Errors is used instead of the errors file
+	Errors errorcache.ErrorsConfig
+	// Services replace container entries before the modules are initialized
+	Services container.Registries
+	// Modules run next to the default ones
+	Modules []Module
+}
+
 type Application struct {
-	Config      *viper.Viper
-	Container   *container.Container
-	Domain      string
-	Environment string
-	Log         *logrus.Logger
-	Routes      []*AppRoutes
+	// Config is the configuration loaded at startup, CurrentConfig follows the reloads
+	Config        *viper.Viper
+	Container     *container.Container
+	Domain        string
+	Environment   string
+	Log           *logrus.Logger
+	Routes        []*AppRoutes
+	AdminRoutes   []*AppRoutes
+	endpoints     []*endpoint
+	certificates  *certificate.Loader
+	configFiles   []string
+	reload        sync.Mutex
+	settings      atomic.Pointer[AppConfig]
+	configSources config.Sources
+	stopWatch     chan struct{}
+	modules       []Module
+	started       []Module
+	cancelRun     context.CancelFunc
+	handedOff     bool
+	options       Options
 }
 
-// Create new application instance
-func New() (app Application, err error) {
+// Create new application instance, the modules run next to the default ones
+func New(modules ...Module) (app *Application, err error) {
+	return NewWithOptions(Options{Modules: modules})
+}
+
+// NewWithOptions - creates the application from the given options instead of the environment
+func NewWithOptions(options Options) (app *Application, err error) {
+
+	app = &Application{options: options}
 
-	app = Application{}
+	log.Println("Starting " + version.Get().String())
 
 	if err = app.initConfig(); err != nil {
 		return
@@ -40,38 +86,21 @@ func New() (app Application, err error) {
 		return
 	}
 
-	app.initEnvironment()
-
-	if err = app.initLogger(); err != nil {
+	if err = app.initSettings(); err != nil {
 		return
 	}
 
-	return app, nil
-}
-
-// Initializes application configuration
-func (a *Application) initConfig() (err error) {
-	path := os.Getenv(constant.EnvConfigPath)
-
-	a.Config = viper.New()
-	a.Config.SetConfigName("config")
-	a.Config.SetConfigType("yaml")
+	app.initEnvironment()
 
-	log.Println("Searching for application configuration file...")
-	if path == "" {
-		p, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-		a.Config.AddConfigPath(p + constant.DefaultConfigPath) // look for config in the working directory
-		log.Println("Loading configs from default location...")
-	} else {
-		a.Config.AddConfigPath(path)
-		log.Printf("Loading configs from location: %s\n", path)
+	if err = app.initLogger(); err != nil {
+		return
 	}
 
-	err = a.Config.ReadInConfig() // Find and read the config file
-	if err != nil { // Handle errors reading the config file
+	if err = app.initModules(append(defaultModules(), options.Modules...)); err != nil {
 		return
 	}
-	return
+
+	return app, nil
 }
 
 // Init container
@@ -79,14 +108,21 @@ func (a *Application) initContainer() (err error) {
 
 	// Register all the required services
 	global := container.New().
-		Register(errorcache.GetRegistry())
+		Register(config.GetRegistry()).
+		Register(errorcache.GetRegistry()).
+		Register(health.GetRegistry()).
+		Register(metrics.GetRegistry()).
+		Register(tracing.GetRegistry()).
+		Register(connlimit.GetRegistry()).
+		Register(ratelimit.GetRegistry()).
+		Register(features.GetRegistry()).
+		Register(scheduler.GetRegistry())
 
 	cont := global.Duplicate()
 
-	// Call service bootstrap methods
-	if err = errorcache.PopulateErrorCodes(cont); err != nil {
-		return
-	}
+	a.initConfigStore(cont)
+
+	cont.Register(a.options.Services)
 
 	a.Container = cont
 
@@ -96,7 +132,7 @@ func (a *Application) initContainer() (err error) {
 // initEnvironment
 func (a *Application) initEnvironment() {
 	a.Domain = a.Config.GetString(constant.AppDomain)
-	a.Environment = a.Config.GetString(constant.EnvConfigPath)
+	a.Environment = a.Config.GetString(constant.AppEnvironment)
 	return
 }
 
@@ -104,51 +140,164 @@ func (a *Application) initEnvironment() {
 func (a *Application) initLogger() (err error) {
 	var log = logrus.New()
 	log.Out = os.Stdout
-	log.Level = logrus.TraceLevel
 	log.Formatter = new(logrus.JSONFormatter)
+	if log.Level, err = logrus.ParseLevel(a.Config.GetString(constant.AppLogLevel)); err != nil {
+		return
+	}
 
 	a.Log = log
 
+	config.GetInstance(a.Container).OnString(constant.AppLogLevel, func(_, next string) {
+		if level, err := logrus.ParseLevel(next); err == nil {
+			a.Log.SetLevel(level)
+			a.Log.Infof("log level changed to %s", level)
+		}
+	})
+
 	return
 }
 
 // Start the application
 func (a *Application) Run() (err error) {
-	var (
-		cert            tls.Certificate
-		TLSCert, TLSKey string
-		pURL            *url.URL
-	)
-
 	handler := a.prepareRoutes()
 
-	if pURL, err = url.ParseRequestURI(a.Domain); err != nil {
+	if err = a.prepareEndpoints(handler); err != nil {
+		a.closeEndpoints()
+		return
+	}
+
+	if err = a.Start(); err != nil {
+		a.closeEndpoints()
 		return
 	}
 
-	ln, err := net.Listen("tcp4", ":"+pURL.Port())
+	return a.serve()
+}
 
-	if err != nil {
-		return fmt.Errorf("failed to start listener with error `%v`", err)
+// Start - starts the modules, their context is cancelled by Shutdown
+func (a *Application) Start() (err error) {
+	var ctx context.Context
+	ctx, a.cancelRun = context.WithCancel(context.Background())
+	if err = a.startModules(ctx); err != nil {
+		a.cancelRun()
 	}
 
-	// if SSL enabled then init TLS
-	if true == a.Config.GetBool(constant.SSLEnabled) {
-		TLSCert = a.Config.GetString(constant.SSLCertFilePath)
-		TLSKey = a.Config.GetString(constant.SSLKeyPath)
+	return
+}
+
+// Handler - returns the router with the middleware, as served on the public listeners
+func (a *Application) Handler() http.Handler {
+	return a.prepareRoutes()
+}
 
-		if TLSCert != "" || TLSKey != "" {
-			if cert, err = tls.LoadX509KeyPair(TLSCert, TLSKey); err != nil {
-				return
+// serve - serves requests on every endpoint until one fails or a termination signal is received
+func (a *Application) serve() (err error) {
+	errCh := make(chan error, len(a.endpoints))
+	for _, e := range a.endpoints {
+		go func(e *endpoint) {
+			errCh <- e.serve()
+		}(e)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	if a.Config.GetBool(constant.UpgradeEnabled) {
+		signal.Notify(sig, syscall.SIGUSR2)
+	}
+	defer signal.Stop(sig)
+
+	// let the parent process drain now that the endpoints are served
+	if rErr := upgrade.Ready(); rErr != nil {
+		log.Printf("failed to report ready to the parent process with error `%v`\n", rErr)
+	}
+
+	for wait := true; wait; {
+		select {
+		case err = <-errCh:
+			wait = false
+		case s := <-sig:
+			switch s {
+			case syscall.SIGHUP:
+				log.Println("Received hangup, reloading config and certificates...")
+				a.ReloadConfig()
+				a.reloadCertificates()
+			case syscall.SIGUSR2:
+				log.Println("Received upgrade request, starting new process...")
+				if uErr := a.upgrade(); uErr != nil {
+					log.Printf("Upgrade failed with error `%v`, keeps serving\n", uErr)
+					continue
+				}
+				log.Println("New process is ready, shutting down...")
+				a.handedOff = true
+				wait = false
+			default:
+				log.Printf("Received %s, shutting down...\n", s)
+				wait = false
 			}
-			tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
-			// update old listener with the upgraded tls one
-			ln = tls.NewListener(ln, tlsConfig)
-			log.Println("Switched to TLS")
-		} else {
-			return errors.New("ssl enabled but certificate missing")
 		}
 	}
 
-	return http.Serve(ln, handler)
+	ctx, cancel := context.WithTimeout(context.Background(), a.Config.GetDuration(constant.ServerShutdownTimeout))
+	defer cancel()
+
+	if err != nil {
+		// one endpoint failed, stop the remaining ones before reporting it
+		a.Shutdown(ctx)
+		return
+	}
+
+	return a.Shutdown(ctx)
+}
+
+// Shutdown - stops accepting connections, drains in-flight requests and flushes pending request logs
+func (a *Application) Shutdown(ctx context.Context) (err error) {
+	// fail readiness first so load balancers stop routing new requests here
+	health.GetInstance(a.Container).Shutdown()
+	a.preStop(ctx)
+
+	if a.certificates != nil {
+		a.certificates.Stop()
+	}
+
+	for _, e := range a.endpoints {
+		if sErr := e.server.Shutdown(ctx); sErr != nil && err == nil {
+			err = fmt.Errorf("failed to drain %s connections with error `%v`", e.name, sErr)
+		}
+	}
+	if a.cancelRun != nil {
+		a.cancelRun()
+	}
+	if mErr := a.stopModules(ctx); mErr != nil && err == nil {
+		err = mErr
+	}
+	if err != nil {
+		return
+	}
+
+	if err = logs.Wait(ctx); err != nil {
+		return fmt.Errorf("failed to flush request logs with error `%v`", err)
+	}
+
+	log.Println("Server stopped")
+
+	return
+}
+
+// preStop - keeps accepting requests while load balancers notice the failing readiness.
+// A handed off process skips it, the new one already serves the same listeners.
+func (a *Application) preStop(ctx context.Context) {
+	delay := a.Config.GetDuration(constant.ServerShutdownDelay)
+	if delay <= 0 || len(a.endpoints) == 0 || a.handedOff {
+		return
+	}
+
+	log.Printf("Not ready, closing the listeners in %s\n", delay)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
 }
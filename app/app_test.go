@@ -0,0 +1,264 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"httpframwork/app/api"
+	"httpframwork/modules/constant"
+	"httpframwork/modules/errorcache"
+	"httpframwork/modules/health"
+	"httpframwork/modules/logger"
+)
+
+// freePort - returns a port nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// newTestApp - creates the application configured by yml, the keys under app are appended
+// to a minimal configuration
+func newTestApp(t *testing.T, yml string) *Application {
+	t.Helper()
+
+	dir := t.TempDir()
+	yml = fmt.Sprintf(`app:
+  name: test
+  domain: http://localhost:8080
+  environment: test
+  app_log: %s
+  log_level: error
+  config:
+    reload_interval: 0s
+`, filepath.Join(dir, "logs")) + yml
+	if err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte(yml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(constant.EnvConfigPath, dir)
+
+	a, err := NewWithOptions(Options{Errors: errorcache.ErrorsConfig{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a
+}
+
+// serveTestApp - starts the application configured by yml on its listeners, with the extra routes
+func serveTestApp(t *testing.T, yml string, routes ...*AppRoutes) *Application {
+	t.Helper()
+
+	a := newTestApp(t, yml)
+	a.Routes = append(a.RouteTable(), routes...)
+	if err := a.prepareEndpoints(a.Handler()); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Start(); err != nil {
+		a.closeEndpoints()
+		t.Fatal(err)
+	}
+	for _, e := range a.endpoints {
+		go e.serve()
+	}
+
+	return a
+}
+
+func TestShutdownDrainsRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	h := &api.Api{}
+	slow := AppRoutes{}.New(h.GetHandler("slow", "/slow", []string{http.MethodGet}, func() {
+		close(started)
+		<-release
+		h.ResponseJSON(map[string]string{"status": "done"})
+	}))
+
+	a := serveTestApp(t, fmt.Sprintf(`  listen:
+    host: 127.0.0.1
+    port: %d
+  server:
+    shutdown_timeout: 5s
+`, freePort(t)), slow)
+	h.Container, h.Config = a.Container, a.Config
+
+	type result struct {
+		code int
+		body string
+		err  error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		res, err := http.Get("http://" + a.endpoints[0].listener.Addr().String() + "/slow")
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		responses <- result{res.StatusCode, string(body), err}
+	}()
+	<-started
+
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Shutdown(context.Background())
+	}()
+
+	// the shutdown waits for the request in progress
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown returned before the request finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+
+	if r := <-responses; r.err != nil || r.code != http.StatusOK || r.body != `{"status":"done"}` {
+		t.Errorf("request cut off by the shutdown: %d %q %v", r.code, r.body, r.err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// the request log is on disk once Shutdown returns
+	files, _ := filepath.Glob(filepath.Join(a.Config.GetString(constant.AppLogFolder), "*.log"))
+	if len(files) != 1 {
+		t.Fatalf("expected the request log, got %v", files)
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "Resource slow") || !strings.Contains(string(content), logs.EndInstanceMsg) {
+		t.Errorf("request log not flushed:\n%s", content)
+	}
+}
+
+func TestHealthStatus(t *testing.T) {
+	a := newTestApp(t, "")
+	get := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		a.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code, rec.Header().Get("Content-Type")
+	}
+
+	for _, path := range []string{"/livez", "/readyz", "/heartbeat"} {
+		if code, ctype := get(path); code != http.StatusOK || ctype != "application/json" {
+			t.Errorf("%s: expected a JSON 200, got %d %q", path, code, ctype)
+		}
+	}
+
+	// readiness and the heartbeat fail once the application shuts down, liveness does not
+	health.GetInstance(a.Container).Shutdown()
+	for path, want := range map[string]int{"/livez": http.StatusOK, "/readyz": http.StatusServiceUnavailable, "/heartbeat": http.StatusServiceUnavailable} {
+		if code, _ := get(path); code != want {
+			t.Errorf("%s: expected %d during the shutdown, got %d", path, want, code)
+		}
+	}
+}
+
+func TestShutdownDelay(t *testing.T) {
+	a := serveTestApp(t, fmt.Sprintf(`  listen:
+    host: 127.0.0.1
+    port: %d
+  server:
+    shutdown_timeout: 5s
+    shutdown_delay: 300ms
+`, freePort(t)))
+
+	address := a.endpoints[0].listener.Addr().String()
+	client := &http.Client{Timeout: 2 * time.Second}
+	ready := func() int {
+		// a new connection every time, the listener must still accept them
+		client.CloseIdleConnections()
+		res, err := client.Get("http://" + address + "/readyz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	if code := ready(); code != http.StatusOK {
+		t.Fatalf("expected ready before the shutdown, got %d", code)
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Shutdown(context.Background())
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected readiness to fail during the delay, got %d", code)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("listeners closed after %s, before the delay", elapsed)
+	}
+	if c, err := net.DialTimeout("tcp", address, time.Second); err == nil {
+		c.Close()
+		t.Error("listener still accepting after the shutdown")
+	}
+}
+
+func TestShutdownDelayHonoursContext(t *testing.T) {
+	conf := viper.New()
+	conf.Set(constant.ServerShutdownDelay, time.Minute)
+	a := &Application{Config: conf, endpoints: []*endpoint{{name: "http"}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	a.preStop(ctx)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("pre-stop ignored the shutdown deadline, returned after %s", elapsed)
+	}
+
+	// the new process serves the listeners after a handoff, there is nothing to wait for
+	a.handedOff = true
+	start = time.Now()
+	a.preStop(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("pre-stop waited after a handoff for %s", elapsed)
+	}
+}
+
+func TestValidateShutdownDelay(t *testing.T) {
+	for delay, problem := range map[string]string{
+		"0s":  "",
+		"5s":  "",
+		"-1s": "app.server.shutdown_delay must be between 0 and app.server.shutdown_timeout",
+		"30s": "app.server.shutdown_delay must be between 0 and app.server.shutdown_timeout",
+	} {
+		_, err := decode(t, map[string]interface{}{constant.ServerShutdownDelay: delay})
+		switch {
+		case problem == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", delay, err)
+		case problem != "" && (err == nil || !strings.Contains(err.Error(), problem)):
+			t.Errorf("%s: expected `%s`, got %v", delay, problem, err)
+		}
+	}
+}
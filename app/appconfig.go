@@ -0,0 +1,529 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"httpframwork/modules/config"
+	"httpframwork/modules/constant"
+	"httpframwork/modules/scheduler"
+)
+
+type (
+	// AppConfig - typed view of the app section of the configuration
+	AppConfig struct {
+		Name        string          `mapstructure:"name"`
+		Domain      string          `mapstructure:"domain"`
+		Environment string          `mapstructure:"environment"`
+		AppLog      string          `mapstructure:"app_log"`
+		LogLevel    string          `mapstructure:"log_level"`
+		Listen      ListenConfig    `mapstructure:"listen"`
+		Server      ServerConfig    `mapstructure:"server"`
+		SSL         SSLConfig       `mapstructure:"ssl"`
+		Upgrade     UpgradeConfig   `mapstructure:"upgrade"`
+		CORS        CORSConfig      `mapstructure:"cors"`
+		Config      ReloadConfig    `mapstructure:"config"`
+		Health      HealthConfig    `mapstructure:"health"`
+		Admin       AdminConfig     `mapstructure:"admin"`
+		Metrics     MetricsConfig   `mapstructure:"metrics"`
+		Tracing     TracingConfig   `mapstructure:"tracing"`
+		Scheduler   SchedConfig     `mapstructure:"scheduler"`
+		Static      StaticConfig    `mapstructure:"static"`
+		RateLimit   RateConfig      `mapstructure:"ratelimit"`
+		Features    map[string]bool `mapstructure:"features"`
+		NewRelic    NewRelicConfig  `mapstructure:"newrelic"`
+		Database    DatabaseConfig  `mapstructure:"database"`
+		Cache       CacheConfig     `mapstructure:"cache"`
+	}
+
+	ListenConfig struct {
+		Enabled    bool   `mapstructure:"enabled"`
+		Redirect   bool   `mapstructure:"redirect"`
+		Host       string `mapstructure:"host"`
+		Port       string `mapstructure:"port"`
+		Network    string `mapstructure:"network"`
+		Socket     string `mapstructure:"socket"`
+		SocketMode string `mapstructure:"socket_mode"`
+		FDName     string `mapstructure:"fd_name"`
+	}
+
+	ServerConfig struct {
+		ShutdownTimeout   time.Duration `mapstructure:"shutdown_timeout"`
+		ShutdownDelay     time.Duration `mapstructure:"shutdown_delay"`
+		ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+		ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+		WriteTimeout      time.Duration `mapstructure:"write_timeout"`
+		IdleTimeout       time.Duration `mapstructure:"idle_timeout"`
+		MaxHeaderBytes    int           `mapstructure:"max_header_bytes"`
+		KeepAlive         bool          `mapstructure:"keep_alive"`
+		HTTP2             HTTP2Config   `mapstructure:"http2"`
+		Connections       ConnConfig    `mapstructure:"connections"`
+	}
+
+	ConnConfig struct {
+		Max          int           `mapstructure:"max"`
+		PerIP        int           `mapstructure:"per_ip"`
+		Mode         string        `mapstructure:"mode"`
+		QueueTimeout time.Duration `mapstructure:"queue_timeout"`
+		MaxQueued    int           `mapstructure:"max_queued"`
+	}
+
+	HTTP2Config struct {
+		Enabled              bool `mapstructure:"enabled"`
+		H2C                  bool `mapstructure:"h2c"`
+		MaxConcurrentStreams int  `mapstructure:"max_concurrent_streams"`
+		MaxReadFrameSize     int  `mapstructure:"max_read_frame_size"`
+	}
+
+	SSLConfig struct {
+		ListenConfig   `mapstructure:",squash"`
+		Cert           string           `mapstructure:"cert"`
+		Key            string           `mapstructure:"key"`
+		ReloadInterval time.Duration    `mapstructure:"reload_interval"`
+		ClientAuth     string           `mapstructure:"client_auth"`
+		ClientCA       string           `mapstructure:"client_ca"`
+		SelfSigned     SelfSignedConfig `mapstructure:"self_signed"`
+		HSTS           HSTSConfig       `mapstructure:"hsts"`
+	}
+
+	SelfSignedConfig struct {
+		Persist bool   `mapstructure:"persist"`
+		Dir     string `mapstructure:"dir"`
+	}
+
+	HSTSConfig struct {
+		MaxAge            int  `mapstructure:"max_age"`
+		IncludeSubdomains bool `mapstructure:"include_subdomains"`
+	}
+
+	UpgradeConfig struct {
+		Enabled      bool          `mapstructure:"enabled"`
+		ReadyTimeout time.Duration `mapstructure:"ready_timeout"`
+	}
+
+	CORSConfig struct {
+		AllowedOrigins []string `mapstructure:"allowed_origins"`
+	}
+
+	ReloadConfig struct {
+		ReloadInterval time.Duration `mapstructure:"reload_interval"`
+	}
+
+	AdminConfig struct {
+		ListenConfig `mapstructure:",squash"`
+		Token        string `mapstructure:"token"`
+	}
+
+	MetricsConfig struct {
+		Enabled bool   `mapstructure:"enabled"`
+		Path    string `mapstructure:"path"`
+		Public  bool   `mapstructure:"public"`
+	}
+
+	TracingConfig struct {
+		Enabled       bool          `mapstructure:"enabled"`
+		ServiceName   string        `mapstructure:"service_name"`
+		SampleRatio   float64       `mapstructure:"sample_ratio"`
+		Exporter      string        `mapstructure:"exporter"`
+		File          string        `mapstructure:"file"`
+		Endpoint      string        `mapstructure:"endpoint"`
+		FlushInterval time.Duration `mapstructure:"flush_interval"`
+		BatchSize     int           `mapstructure:"batch_size"`
+		Timeout       time.Duration `mapstructure:"timeout"`
+	}
+
+	RateConfig struct {
+		Rate  float64 `mapstructure:"rate"`
+		Burst int     `mapstructure:"burst"`
+	}
+
+	StaticConfig struct {
+		Enabled bool   `mapstructure:"enabled"`
+		Path    string `mapstructure:"path"`
+		Dir     string `mapstructure:"dir"`
+		SPA     bool   `mapstructure:"spa"`
+	}
+
+	SchedConfig struct {
+		Enabled    bool             `mapstructure:"enabled"`
+		Timezone   string           `mapstructure:"timezone"`
+		LogCleanup LogCleanupConfig `mapstructure:"log_cleanup"`
+	}
+
+	LogCleanupConfig struct {
+		Spec      string        `mapstructure:"spec"`
+		Retention time.Duration `mapstructure:"retention"`
+	}
+
+	HealthConfig struct {
+		Timeout  time.Duration `mapstructure:"timeout"`
+		CacheTTL time.Duration `mapstructure:"cache_ttl"`
+	}
+
+	NewRelicConfig struct {
+		Enabled bool   `mapstructure:"enabled"`
+		Key     string `mapstructure:"key"`
+		Name    string `mapstructure:"name"`
+	}
+
+	DatabaseConfig struct {
+		Driver   string `mapstructure:"driver"`
+		Host     string `mapstructure:"host"`
+		User     string `mapstructure:"user"`
+		Password string `mapstructure:"password"`
+		DBName   string `mapstructure:"db_name"`
+	}
+
+	CacheConfig struct {
+		Name   string `mapstructure:"name"`
+		Server string `mapstructure:"server"`
+	}
+
+	// ConfigErrors - every problem found while validating the configuration
+	ConfigErrors []string
+)
+
+// Error returns all the problems, one per line
+func (e ConfigErrors) Error() string {
+	return fmt.Sprintf("invalid configuration, %d problem(s):\n  - %s", len(e), strings.Join(e, "\n  - "))
+}
+
+// DecodeConfig - decodes and validates the app section, unknown keys are returned as warnings
+func DecodeConfig(conf *viper.Viper) (settings *AppConfig, warnings []string, err error) {
+	var (
+		root struct {
+			App AppConfig `mapstructure:"app"`
+		}
+		md mapstructure.Metadata
+	)
+
+	if err = conf.Unmarshal(&root, func(c *mapstructure.DecoderConfig) { c.Metadata = &md }); err != nil {
+		return nil, nil, ConfigErrors{err.Error()}
+	}
+
+	for _, k := range md.Unused {
+		warnings = append(warnings, fmt.Sprintf("unknown config key %s", k))
+	}
+
+	settings = &root.App
+	if errs := settings.Validate(); len(errs) > 0 {
+		return nil, warnings, errs
+	}
+
+	return
+}
+
+// Validate - checks the configuration and reports every problem at once
+func (c *AppConfig) Validate() (errs ConfigErrors) {
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	for _, r := range [][2]string{
+		{constant.AppDomain, c.Domain},
+		{constant.AppEnvironment, c.Environment},
+		{constant.AppLogFolder, c.AppLog},
+	} {
+		if r[1] == "" {
+			add("%s is required", r[0])
+		}
+	}
+
+	domainPort := ""
+	if c.Domain != "" {
+		if u, err := url.ParseRequestURI(c.Domain); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			add("%s must be an absolute http(s) URL, got `%s`", constant.AppDomain, c.Domain)
+		} else {
+			domainPort = u.Port()
+		}
+	}
+
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		add("%s: %v", constant.AppLogLevel, err)
+	}
+
+	if !c.Listen.Enabled && !c.SSL.Enabled {
+		add("at least one of %s and %s must be true", constant.ListenEnabled, constant.SSLEnabled)
+	}
+	if c.Listen.Enabled {
+		c.Listen.validate("app.listen", domainPort, add)
+	}
+	if c.Listen.Redirect && !c.SSL.Enabled {
+		add("%s requires %s", constant.ListenRedirect, constant.SSLEnabled)
+	}
+
+	if c.SSL.Enabled {
+		c.SSL.validate(c.Environment, add)
+	}
+
+	if c.Listen.Enabled && c.SSL.Enabled {
+		plain, secure := c.Listen.tcpPort(domainPort), c.SSL.tcpPort(constant.DefaultSSLPort)
+		sameHost := c.Listen.Host == c.SSL.Host || c.Listen.Host == "" || c.SSL.Host == ""
+		if plain != "" && plain == secure && sameHost {
+			add("%s and %s both bind port %s", constant.ListenPort, constant.SSLPort, plain)
+		}
+	}
+
+	c.Server.validate(add)
+
+	if c.Admin.Enabled {
+		c.Admin.ListenConfig.validate("app.admin", "", add)
+		if c.Admin.Token == "" {
+			add("%s is required when %s is true", constant.AdminToken, constant.AdminEnabled)
+		}
+	}
+
+	if c.Metrics.Enabled && !strings.HasPrefix(c.Metrics.Path, "/") {
+		add("%s must start with /, got `%s`", constant.MetricsPath, c.Metrics.Path)
+	}
+
+	if c.Tracing.Enabled {
+		c.Tracing.validate(add)
+	}
+
+	if c.Scheduler.Enabled {
+		c.Scheduler.validate(add)
+	}
+
+	if c.RateLimit.Rate < 0 {
+		add("%s must not be negative", constant.RateLimitRate)
+	}
+	if c.RateLimit.Rate > 0 && c.RateLimit.Burst < 1 {
+		add("%s must be at least 1 when %s is set", constant.RateLimitBurst, constant.RateLimitRate)
+	}
+
+	if c.Static.Enabled {
+		if !strings.HasPrefix(c.Static.Path, "/") {
+			add("%s must start with /, got `%s`", constant.StaticPath, c.Static.Path)
+		}
+		if info, err := os.Stat(c.Static.Dir); err != nil || !info.IsDir() {
+			add("%s must be an existing directory, got `%s`", constant.StaticDir, c.Static.Dir)
+		}
+	}
+
+	if c.Health.Timeout <= 0 {
+		add("%s must be positive", constant.HealthTimeout)
+	}
+	if c.Health.CacheTTL < 0 {
+		add("%s must not be negative", constant.HealthCacheTTL)
+	}
+
+	return
+}
+
+// validate - checks one listener section
+func (l *ListenConfig) validate(section, domainPort string, add func(string, ...interface{})) {
+	switch l.Network {
+	case "tcp", "tcp4", "tcp6":
+		port := l.Port
+		if port == "" {
+			port = domainPort
+		}
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			add("%s.port must be between 1 and 65535, got `%s`", section, port)
+		}
+	case "unix":
+		if l.Socket == "" {
+			add("%s.socket is required for unix listeners", section)
+		}
+		if _, err := strconv.ParseUint(l.SocketMode, 8, 32); err != nil {
+			add("%s.socket_mode must be an octal file mode, got `%s`", section, l.SocketMode)
+		}
+	case "systemd":
+	default:
+		add("%s.network must be one of tcp, tcp4, tcp6, unix, systemd, got `%s`", section, l.Network)
+	}
+}
+
+// tcpPort - returns the port a tcp listener binds, empty for other networks
+func (l *ListenConfig) tcpPort(defaultPort string) string {
+	switch l.Network {
+	case "tcp", "tcp4", "tcp6":
+		if l.Port == "" {
+			return defaultPort
+		}
+		return l.Port
+	}
+
+	return ""
+}
+
+// validate - checks the TLS section, without a port it binds 443
+func (s *SSLConfig) validate(environment string, add func(string, ...interface{})) {
+	s.ListenConfig.validate("app.ssl", constant.DefaultSSLPort, add)
+
+	switch {
+	case s.Cert == "" && s.Key == "":
+		if environment != constant.EnvironmentLocal {
+			add("%s and %s are required outside the local environment", constant.SSLCertFilePath, constant.SSLKeyPath)
+		}
+	case s.Cert == "" || s.Key == "":
+		add("%s and %s must be set together", constant.SSLCertFilePath, constant.SSLKeyPath)
+	default:
+		for _, f := range [][2]string{{constant.SSLCertFilePath, s.Cert}, {constant.SSLKeyPath, s.Key}} {
+			if _, err := os.Stat(f[1]); err != nil {
+				add("%s: %v", f[0], err)
+			}
+		}
+	}
+
+	switch s.ClientAuth {
+	case "", "none", "request":
+	case "require", "verify_if_given":
+		if s.ClientCA == "" {
+			add("%s is required when %s is `%s`", constant.SSLClientCA, constant.SSLClientAuth, s.ClientAuth)
+		}
+	default:
+		add("%s must be one of none, request, require, verify_if_given, got `%s`", constant.SSLClientAuth, s.ClientAuth)
+	}
+
+	if s.ClientCA != "" {
+		if _, err := os.Stat(s.ClientCA); err != nil {
+			add("%s: %v", constant.SSLClientCA, err)
+		}
+	}
+
+	if s.HSTS.MaxAge < 0 {
+		add("%s must not be negative", constant.HSTSMaxAge)
+	}
+}
+
+// validate - checks the server limits
+func (s *ServerConfig) validate(add func(string, ...interface{})) {
+	timeouts := []struct {
+		key   string
+		value time.Duration
+	}{
+		{constant.ServerReadTimeout, s.ReadTimeout},
+		{constant.ServerReadHeaderTimeout, s.ReadHeaderTimeout},
+		{constant.ServerWriteTimeout, s.WriteTimeout},
+		{constant.ServerIdleTimeout, s.IdleTimeout},
+	}
+	for _, t := range timeouts {
+		if t.value < 0 {
+			add("%s must not be negative", t.key)
+		}
+	}
+
+	if s.ShutdownTimeout <= 0 {
+		add("%s must be positive", constant.ServerShutdownTimeout)
+	}
+
+	// the delay is taken from the shutdown timeout, some of it must be left to drain the requests
+	if s.ShutdownDelay < 0 || s.ShutdownDelay >= s.ShutdownTimeout {
+		add("%s must be between 0 and %s", constant.ServerShutdownDelay, constant.ServerShutdownTimeout)
+	}
+
+	if s.MaxHeaderBytes <= 0 {
+		add("%s must be positive", constant.ServerMaxHeaderBytes)
+	}
+
+	if f := s.HTTP2.MaxReadFrameSize; f != 0 && (f < 16<<10 || f > 1<<24-1) {
+		add("%s must be between 16384 and 16777215, got %d", constant.HTTP2MaxReadFrameSize, f)
+	}
+
+	c := s.Connections
+	if c.Max < 0 {
+		add("%s must not be negative", constant.ConnectionsMax)
+	}
+	if c.PerIP < 0 {
+		add("%s must not be negative", constant.ConnectionsPerIP)
+	}
+	switch c.Mode {
+	case "reject":
+	case "queue":
+		if c.QueueTimeout <= 0 {
+			add("%s must be positive when %s is queue", constant.ConnectionsQueueTimeout, constant.ConnectionsMode)
+		}
+		if c.MaxQueued <= 0 {
+			add("%s must be positive when %s is queue", constant.ConnectionsMaxQueued, constant.ConnectionsMode)
+		}
+	default:
+		add("%s must be one of reject, queue, got `%s`", constant.ConnectionsMode, c.Mode)
+	}
+}
+
+// validate - checks the tracing sampling and exporter
+func (t *TracingConfig) validate(add func(string, ...interface{})) {
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		add("%s must be between 0 and 1, got %v", constant.TracingSampleRatio, t.SampleRatio)
+	}
+
+	switch t.Exporter {
+	case "none":
+	case "file":
+		if t.File == "" {
+			add("%s is required when %s is file", constant.TracingFile, constant.TracingExporter)
+		}
+	case "otlp":
+		if u, err := url.ParseRequestURI(t.Endpoint); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			add("%s must be an absolute http(s) URL, got `%s`", constant.TracingEndpoint, t.Endpoint)
+		}
+	default:
+		add("%s must be one of none, file, otlp, got `%s`", constant.TracingExporter, t.Exporter)
+	}
+
+	if t.FlushInterval <= 0 {
+		add("%s must be positive", constant.TracingFlushInterval)
+	}
+	if t.BatchSize <= 0 {
+		add("%s must be positive", constant.TracingBatchSize)
+	}
+}
+
+// validate - checks the time zone and the log cleanup job
+func (s *SchedConfig) validate(add func(string, ...interface{})) {
+	location, err := scheduler.LoadLocation(s.Timezone)
+	if err != nil {
+		add("%s: %v", constant.SchedulerTimezone, err)
+		location = time.Local
+	}
+
+	if s.LogCleanup.Retention < 0 {
+		add("%s must not be negative", constant.LogCleanupRetention)
+	}
+	if s.LogCleanup.Retention > 0 {
+		if _, err := scheduler.Parse(s.LogCleanup.Spec, location); err != nil {
+			add("%s: %v", constant.LogCleanupSpec, err)
+		}
+	}
+}
+
+// Settings - returns the typed view of the config in effect, it follows reloads and must not be modified
+func (a *Application) Settings() *AppConfig {
+	return a.settings.Load()
+}
+
+// initSettings - decodes the typed configuration and keeps it in sync with reloads
+func (a *Application) initSettings() (err error) {
+	settings, warnings, err := DecodeConfig(a.Config)
+	for _, w := range warnings {
+		log.Println("Warning: " + w)
+	}
+	if err != nil {
+		return
+	}
+
+	a.settings.Store(settings)
+
+	config.GetInstance(a.Container).
+		Validate(func(conf *viper.Viper) (err error) {
+			_, _, err = DecodeConfig(conf)
+			return
+		}).
+		Subscribe("", func(_, next *viper.Viper, _ []config.Change) {
+			if settings, _, err := DecodeConfig(next); err == nil {
+				a.settings.Store(settings)
+			}
+		})
+
+	return
+}
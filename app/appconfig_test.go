@@ -0,0 +1,143 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"httpframwork/modules/config"
+)
+
+// decode - loads the defaults, a minimal valid configuration and the overrides, then decodes it
+func decode(t *testing.T, overrides map[string]interface{}) (*AppConfig, error) {
+	t.Helper()
+
+	conf := viper.New()
+	setDefaults(conf)
+	if _, err := config.LoadMap(conf, map[string]interface{}{
+		"app": map[string]interface{}{
+			"name":        "test",
+			"domain":      "http://localhost:8080",
+			"environment": "local",
+			"app_log":     t.TempDir(),
+			"log_level":   "error",
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range overrides {
+		conf.Set(k, v)
+	}
+
+	settings, _, err := DecodeConfig(conf)
+	return settings, err
+}
+
+func TestValidateListeners(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]interface{}
+		problem   string
+	}{
+		{"plain listener only", nil, ""},
+		{"tls on the default port", map[string]interface{}{"app.ssl.enabled": true}, ""},
+		{"tls on its own port", map[string]interface{}{"app.ssl.enabled": true, "app.ssl.port": "8443"}, ""},
+		{"tls on the plain port", map[string]interface{}{"app.ssl.enabled": true, "app.ssl.port": "8080"},
+			"app.listen.port and app.ssl.port both bind port 8080"},
+		{"tls on the domain port", map[string]interface{}{"app.ssl.enabled": true, "app.listen.port": "", "app.ssl.port": "8080"},
+			"both bind port 8080"},
+		{"same port on other hosts", map[string]interface{}{"app.ssl.enabled": true, "app.ssl.port": "8080",
+			"app.listen.host": "127.0.0.1", "app.ssl.host": "127.0.0.2"}, ""},
+		{"tls on a unix socket", map[string]interface{}{"app.ssl.enabled": true, "app.ssl.port": "8080",
+			"app.ssl.network": "unix", "app.ssl.socket": "/tmp/test.sock"}, ""},
+		{"no listener", map[string]interface{}{"app.listen.enabled": false}, "at least one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decode(t, tt.overrides)
+			switch {
+			case tt.problem == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.problem != "" && (err == nil || !strings.Contains(err.Error(), tt.problem)):
+				t.Fatalf("expected `%s`, got %v", tt.problem, err)
+			}
+		})
+	}
+}
+
+func TestValidateCertificatePair(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]interface{}
+		problem   string
+	}{
+		{"generated in local", map[string]interface{}{"app.ssl.enabled": true}, ""},
+		{"generated outside local", map[string]interface{}{"app.ssl.enabled": true, "app.environment": "production"},
+			"app.ssl.cert and app.ssl.key are required outside the local environment"},
+		{"certificate without key", map[string]interface{}{"app.ssl.enabled": true, "app.ssl.cert": "./server.crt"},
+			"app.ssl.cert and app.ssl.key must be set together"},
+		{"key without certificate", map[string]interface{}{"app.ssl.enabled": true, "app.ssl.key": "./server.key"},
+			"app.ssl.cert and app.ssl.key must be set together"},
+		{"missing files", map[string]interface{}{"app.ssl.enabled": true, "app.ssl.cert": "/nope.crt", "app.ssl.key": "/nope.key"},
+			"app.ssl.key: stat /nope.key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decode(t, tt.overrides)
+			switch {
+			case tt.problem == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.problem != "" && (err == nil || !strings.Contains(err.Error(), tt.problem)):
+				t.Fatalf("expected `%s`, got %v", tt.problem, err)
+			}
+		})
+	}
+}
+
+func TestListenAddressDefaults(t *testing.T) {
+	conf := viper.New()
+	setDefaults(conf)
+	a := &Application{Config: conf, Domain: "http://localhost:8080"}
+
+	for _, tt := range []struct {
+		keys listenKeys
+		want string
+	}{
+		{plainListener, ":8080"},
+		{tlsListener, ":443"},
+	} {
+		if got, err := a.listenAddress(tt.keys); err != nil || got != tt.want {
+			t.Errorf("%s: expected %s, got %s (%v)", tt.keys.name, tt.want, got, err)
+		}
+	}
+}
+
+func TestValidateScheduler(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]interface{}
+		problem   string
+	}{
+		{"local time zone", map[string]interface{}{"app.scheduler.timezone": ""}, ""},
+		{"named time zone", map[string]interface{}{"app.scheduler.timezone": "UTC"}, ""},
+		{"unknown time zone", map[string]interface{}{"app.scheduler.timezone": "Mars/Olympus"}, "app.scheduler.timezone:"},
+		{"sunday as a range end", map[string]interface{}{"app.scheduler.log_cleanup.retention": "72h",
+			"app.scheduler.log_cleanup.spec": "0 3 * * 5-7"}, ""},
+		{"invalid cleanup spec", map[string]interface{}{"app.scheduler.log_cleanup.retention": "72h",
+			"app.scheduler.log_cleanup.spec": "0 3 * *"}, "app.scheduler.log_cleanup.spec: expected 5 fields"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decode(t, tt.overrides)
+			switch {
+			case tt.problem == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.problem != "" && (err == nil || !strings.Contains(err.Error(), tt.problem)):
+				t.Fatalf("expected `%s`, got %v", tt.problem, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+// Package apptest boots an Application in process for tests, from config and error
+// catalogs given in code, and provides fluent helpers to assert on its responses.
+package apptest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+	"httpframwork/app"
+	"httpframwork/modules/container"
+	"httpframwork/modules/errorcache"
+)
+
+type (
+	// App is an application booted for one test, it is stopped when the test ends
+	App struct {
+		*app.Application
+		t       testing.TB
+		handler http.Handler
+		server  *httptest.Server
+	}
+
+	// Option changes how the application is built
+	Option func(*settings)
+
+	// Register builds a route the way the api.Register functions do
+	Register func(cont *container.Container, conf *viper.Viper) (string, string, []string, http.HandlerFunc)
+
+	settings struct {
+		options app.Options
+		routes  []Register
+	}
+)
+
+// boot serializes application creation, New replaces the global container
+var boot sync.Mutex
+
+// Config returns the minimal valid configuration, logs go to dir
+func Config(dir string) map[string]interface{} {
+	return map[string]interface{}{
+		"app": map[string]interface{}{
+			"name":        "apptest",
+			"domain":      "http://localhost:8080",
+			"environment": "test",
+			"app_log":     dir,
+			"log_level":   "error",
+			"config": map[string]interface{}{
+				"reload_interval": "0s",
+			},
+		},
+	}
+}
+
+// WithConfig sets a config key, dotted keys like app.cors.allowed_origins address nested values
+func WithConfig(key string, value interface{}) Option {
+	return func(s *settings) {
+		m := s.options.Config
+		parts := strings.Split(key, ".")
+		for _, p := range parts[:len(parts)-1] {
+			next, ok := m[p].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				m[p] = next
+			}
+			m = next
+		}
+		m[parts[len(parts)-1]] = value
+	}
+}
+
+// WithErrors replaces the error catalog
+func WithErrors(errors errorcache.ErrorsConfig) Option {
+	return func(s *settings) {
+		s.options.Errors = errors
+	}
+}
+
+// WithError adds one error to the catalog
+func WithError(code string, status int, message string) Option {
+	return func(s *settings) {
+		if s.options.Errors == nil {
+			s.options.Errors = make(errorcache.ErrorsConfig)
+		}
+		s.options.Errors[code] = errorcache.Error{Status: status, Message: message}
+	}
+}
+
+// WithService replaces the container entry under key, e.g. with a fake
+func WithService(key string, value interface{}) Option {
+	return func(s *settings) {
+		s.options.Services = append(s.options.Services, container.Registry{Key: key, Value: value})
+	}
+}
+
+// WithModules runs the modules next to the default ones
+func WithModules(modules ...app.Module) Option {
+	return func(s *settings) {
+		s.options.Modules = append(s.options.Modules, modules...)
+	}
+}
+
+// WithRoute registers a route next to the application routes, e.g. WithRoute(api.RegisterSample)
+func WithRoute(register Register) Option {
+	return func(s *settings) {
+		s.routes = append(s.routes, register)
+	}
+}
+
+// New builds and starts the application, failing the test when it can't.
+// The configuration starts from Config with the log folder in a temporary directory.
+func New(t testing.TB, opts ...Option) *App {
+	t.Helper()
+
+	s := &settings{
+		options: app.Options{
+			Config: Config(t.TempDir()),
+			Errors: errorcache.ErrorsConfig{},
+		},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+
+	boot.Lock()
+	application, err := app.NewWithOptions(s.options)
+	boot.Unlock()
+	if err != nil {
+		t.Fatalf("apptest: failed to create the application: %v", err)
+	}
+
+	routes := application.RouteTable()
+	for _, register := range s.routes {
+		routes = append(routes, app.AppRoutes{}.New(register(application.Container, application.Config)))
+	}
+	application.Routes = routes
+
+	if err = application.Start(); err != nil {
+		t.Fatalf("apptest: failed to start the application: %v", err)
+	}
+
+	a := &App{
+		Application: application,
+		t:           t,
+		handler:     application.Handler(),
+	}
+
+	t.Cleanup(func() {
+		if a.server != nil {
+			a.server.Close()
+		}
+		if err := application.Shutdown(context.Background()); err != nil {
+			t.Errorf("apptest: failed to stop the application: %v", err)
+		}
+	})
+
+	return a
+}
+
+// Handler returns the router with the middleware, to serve requests without a listener
+func (a *App) Handler() http.Handler {
+	return a.handler
+}
+
+// Server returns a server listening on a loopback address, started on first use
+func (a *App) Server() *httptest.Server {
+	if a.server == nil {
+		a.server = httptest.NewServer(a.handler)
+	}
+
+	return a.server
+}
+
+// Service returns the container entry under key
+func (a *App) Service(key string) interface{} {
+	return a.Container.Get(key)
+}
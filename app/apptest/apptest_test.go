@@ -0,0 +1,149 @@
+package apptest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+	"httpframwork/app"
+	"httpframwork/app/api"
+	"httpframwork/modules/constant"
+	"httpframwork/modules/container"
+	"httpframwork/modules/health"
+)
+
+// registerTeapot - answers with the error registered under E_TEAPOT
+func registerTeapot(cont *container.Container, conf *viper.Viper) (string, string, []string, http.HandlerFunc) {
+	h := &api.Api{Container: cont, Config: conf}
+
+	return h.GetHandler("teapot", "/teapot", []string{http.MethodGet}, func() {
+		h.ResponseError("E_TEAPOT", "coffee")
+	})
+}
+
+// registerEcho - writes back what the request carried
+func registerEcho(*container.Container, *viper.Viper) (string, string, []string, http.HandlerFunc) {
+	return "echo", "/echo", []string{http.MethodPost}, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"header": r.Header.Get("X-Test"),
+			"query":  r.URL.Query().Get("q"),
+			"body":   string(body),
+			"remote": r.RemoteAddr,
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	var a *App
+	t.Run("boot", func(t *testing.T) {
+		a = New(t)
+
+		a.GET("/livez").Do().ExpectStatus(http.StatusOK).ExpectJSONPath("status", health.StatusPass)
+		a.GET("/readyz").Do().ExpectStatus(http.StatusOK)
+
+		if a.Config.GetString(constant.AppName) != "apptest" || a.Config.GetString(constant.AppEnvironment) != "test" {
+			t.Errorf("expected the apptest config, got %v", a.Config.AllSettings())
+		}
+		if _, ok := a.Service(health.InstanceKey).(*health.Registry); !ok {
+			t.Errorf("expected the health registry in the container")
+		}
+	})
+
+	// the application is stopped when the test ends
+	if !health.GetInstance(a.Container).ShuttingDown() {
+		t.Error("application still running after the test")
+	}
+}
+
+func TestWithConfig(t *testing.T) {
+	s := &settings{options: app.Options{Config: Config(t.TempDir())}}
+	WithConfig("app.cors.allowed_origins", []string{"https://a.test"})(s)
+	WithConfig("app.log_level", "debug")(s)
+	// a value is replaced by the map holding the nested key
+	WithConfig("app.name.first", "x")(s)
+
+	values := s.options.Config["app"].(map[string]interface{})
+	if got := values["cors"]; !reflect.DeepEqual(got, map[string]interface{}{"allowed_origins": []string{"https://a.test"}}) {
+		t.Errorf("unexpected nested value %v", got)
+	}
+	if values["log_level"] != "debug" || !reflect.DeepEqual(values["name"], map[string]interface{}{"first": "x"}) {
+		t.Errorf("unexpected values %v", values)
+	}
+	if values["environment"] != "test" {
+		t.Errorf("sibling keys must be kept, got %v", values)
+	}
+
+	a := New(t, WithConfig(constant.CORSAllowedOrigins, []string{"https://a.test"}), WithConfig(constant.AppLogLevel, "warn"))
+	if got := a.Config.GetStringSlice(constant.CORSAllowedOrigins); !reflect.DeepEqual(got, []string{"https://a.test"}) {
+		t.Errorf("expected the configured origins, got %v", got)
+	}
+	if got := a.Log.GetLevel().String(); got != "warning" {
+		t.Errorf("expected the configured log level, got %s", got)
+	}
+}
+
+func TestWithError(t *testing.T) {
+	a := New(t, WithError("E_TEAPOT", http.StatusTeapot, "no %s here"), WithRoute(registerTeapot))
+
+	a.GET("/teapot").Do().
+		ExpectErrorCode("E_TEAPOT").
+		ExpectJSON(api.ErrorResponse{Code: "E_TEAPOT", Message: "no coffee here"})
+}
+
+func TestWithService(t *testing.T) {
+	fake := &struct{ name string }{"fake"}
+	a := New(t, WithService("Fake", fake))
+
+	if a.Service("Fake") != fake {
+		t.Errorf("expected the fake service, got %v", a.Service("Fake"))
+	}
+}
+
+func TestRequest(t *testing.T) {
+	a := New(t, WithRoute(registerEcho))
+
+	var echo map[string]string
+	a.POST("/echo").Header("X-Test", "yes").Query("q", "a b").JSON(map[string]int{"n": 1}).Do().
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Content-Type", "application/json").
+		DecodeJSON(&echo)
+
+	want := map[string]string{"header": "yes", "query": "a b", "body": `{"n":1}`, "remote": "127.0.0.1:40000"}
+	if !reflect.DeepEqual(echo, want) {
+		t.Errorf("expected %v, got %v", want, echo)
+	}
+}
+
+func TestOverNetwork(t *testing.T) {
+	var url string
+	t.Run("serve", func(t *testing.T) {
+		a := New(t, WithRoute(registerEcho))
+
+		res := a.POST("/echo").Header("X-Test", "yes").Query("q", "a b").Body("text/plain", []byte("hi")).OverNetwork().Do().
+			ExpectStatus(http.StatusOK).
+			ExpectJSONPath("header", "yes").
+			ExpectJSONPath("query", "a b").
+			ExpectJSONPath("body", "hi")
+
+		// the request came through a real connection
+		if remote, _ := res.JSONPath("remote"); remote == "127.0.0.1:40000" {
+			t.Errorf("expected the address of the client connection, got %v", remote)
+		}
+		if a.Server() != a.Server() {
+			t.Error("expected the server to be started once")
+		}
+		url = a.Server().URL
+	})
+
+	// the server is closed with the application
+	if res, err := http.Get(url + "/livez"); err == nil {
+		res.Body.Close()
+		t.Error("test server still listening after the test")
+	}
+}
@@ -0,0 +1,287 @@
+package apptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"httpframwork/app/api"
+	"httpframwork/modules/errorcache"
+)
+
+type (
+	// Request is built fluently and sent to the application handler by Do
+	Request struct {
+		app     *App
+		req     *http.Request
+		body    []byte
+		onWire  bool
+		failure error
+	}
+
+	// Response records what the application answered, its Expect methods report to the test
+	Response struct {
+		t        testing.TB
+		app      *App
+		Code     int
+		Header   http.Header
+		Body     []byte
+		decoded  interface{}
+		decodeOK bool
+	}
+)
+
+// Request starts a request to path on the application
+func (a *App) Request(method, path string) *Request {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = "127.0.0.1:40000"
+
+	return &Request{app: a, req: req}
+}
+
+// GET starts a GET request
+func (a *App) GET(path string) *Request { return a.Request(http.MethodGet, path) }
+
+// POST starts a POST request
+func (a *App) POST(path string) *Request { return a.Request(http.MethodPost, path) }
+
+// PUT starts a PUT request
+func (a *App) PUT(path string) *Request { return a.Request(http.MethodPut, path) }
+
+// DELETE starts a DELETE request
+func (a *App) DELETE(path string) *Request { return a.Request(http.MethodDelete, path) }
+
+// Header sets a request header
+func (r *Request) Header(key, value string) *Request {
+	r.req.Header.Set(key, value)
+	return r
+}
+
+// Query adds a query parameter
+func (r *Request) Query(key, value string) *Request {
+	q := r.req.URL.Query()
+	q.Add(key, value)
+	r.req.URL.RawQuery = q.Encode()
+	r.req.RequestURI = r.req.URL.RequestURI()
+	return r
+}
+
+// Body sets a raw request body
+func (r *Request) Body(contentType string, body []byte) *Request {
+	r.body = body
+	r.req.Header.Set("Content-Type", contentType)
+	return r
+}
+
+// JSON sets v encoded as the JSON request body
+func (r *Request) JSON(v interface{}) *Request {
+	b, err := json.Marshal(v)
+	if err != nil {
+		r.failure = err
+	}
+
+	return r.Body("application/json", b)
+}
+
+// OverNetwork sends the request through the test server instead of calling the handler
+func (r *Request) OverNetwork() *Request {
+	r.onWire = true
+	return r
+}
+
+// Do sends the request and records the response
+func (r *Request) Do() *Response {
+	t := r.app.t
+	t.Helper()
+
+	if r.failure != nil {
+		t.Fatalf("apptest: failed to build the request: %v", r.failure)
+	}
+
+	res := &Response{t: t, app: r.app}
+
+	if !r.onWire {
+		r.req.Body = ioutil.NopCloser(bytes.NewReader(r.body))
+		r.req.ContentLength = int64(len(r.body))
+
+		rec := httptest.NewRecorder()
+		r.app.handler.ServeHTTP(rec, r.req)
+
+		res.Code, res.Header, res.Body = rec.Code, rec.Header(), rec.Body.Bytes()
+		return res
+	}
+
+	req, err := http.NewRequest(r.req.Method, r.app.Server().URL+r.req.URL.RequestURI(), bytes.NewReader(r.body))
+	if err != nil {
+		t.Fatalf("apptest: failed to build the request: %v", err)
+	}
+	req.Header = r.req.Header.Clone()
+
+	wire, err := r.app.Server().Client().Do(req)
+	if err != nil {
+		t.Fatalf("apptest: %s %s failed: %v", req.Method, req.URL, err)
+	}
+	defer wire.Body.Close()
+
+	res.Code, res.Header = wire.StatusCode, wire.Header
+	res.Body, _ = ioutil.ReadAll(io.LimitReader(wire.Body, 32<<20))
+
+	return res
+}
+
+// ExpectStatus checks the status code
+func (r *Response) ExpectStatus(code int) *Response {
+	r.t.Helper()
+
+	if r.Code != code {
+		r.t.Errorf("expected status %d, got %d with body %s", code, r.Code, r.Body)
+	}
+
+	return r
+}
+
+// ExpectHeader checks a response header
+func (r *Response) ExpectHeader(key, value string) *Response {
+	r.t.Helper()
+
+	if got := r.Header.Get(key); got != value {
+		r.t.Errorf("expected header %s `%s`, got `%s`", key, value, got)
+	}
+
+	return r
+}
+
+// ExpectBodyContains checks the raw body contains s
+func (r *Response) ExpectBodyContains(s string) *Response {
+	r.t.Helper()
+
+	if !strings.Contains(string(r.Body), s) {
+		r.t.Errorf("expected body to contain `%s`, got %s", s, r.Body)
+	}
+
+	return r
+}
+
+// ExpectJSON checks the body is JSON equal to expected, compared after both are decoded
+func (r *Response) ExpectJSON(expected interface{}) *Response {
+	r.t.Helper()
+
+	b, err := json.Marshal(expected)
+	if err != nil {
+		r.t.Fatalf("apptest: failed to encode the expected body: %v", err)
+	}
+
+	var want interface{}
+	json.Unmarshal(b, &want)
+
+	if got, ok := r.json(); ok && !reflect.DeepEqual(got, want) {
+		r.t.Errorf("expected JSON body %s, got %s", b, r.Body)
+	}
+
+	return r
+}
+
+// ExpectJSONPath checks the value found at the dotted path, e.g. checks.0.status.
+// Numbers are compared as float64 after decoding, expected ints are converted.
+func (r *Response) ExpectJSONPath(path string, expected interface{}) *Response {
+	r.t.Helper()
+
+	got, ok := r.JSONPath(path)
+	if !ok {
+		r.t.Errorf("expected JSON path %s in body %s", path, r.Body)
+		return r
+	}
+
+	b, _ := json.Marshal(expected)
+	var want interface{}
+	json.Unmarshal(b, &want)
+
+	if !reflect.DeepEqual(got, want) {
+		r.t.Errorf("expected %s to be %s, got %v", path, b, got)
+	}
+
+	return r
+}
+
+// ExpectErrorCode checks the response was written by Api.ResponseError for the errorcache code
+func (r *Response) ExpectErrorCode(code string) *Response {
+	r.t.Helper()
+
+	e := errorcache.GetInstance(r.app.Container).GetError(code)
+	if e.Status == 0 {
+		r.t.Fatalf("apptest: error code %s is not in the catalog", code)
+	}
+
+	r.ExpectStatus(e.Status)
+
+	var body api.ErrorResponse
+	if err := json.Unmarshal(r.Body, &body); err != nil {
+		r.t.Errorf("expected error %s, got a body that is not JSON: %s", code, r.Body)
+		return r
+	}
+	if body.Code != code {
+		r.t.Errorf("expected error %s, got %s with message `%s`", code, body.Code, body.Message)
+	}
+
+	return r
+}
+
+// DecodeJSON decodes the body into v, failing the test when it is not valid JSON
+func (r *Response) DecodeJSON(v interface{}) *Response {
+	r.t.Helper()
+
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.t.Fatalf("apptest: failed to decode body %s: %v", r.Body, err)
+	}
+
+	return r
+}
+
+// JSONPath returns the value found at the dotted path, array elements are addressed by index
+func (r *Response) JSONPath(path string) (interface{}, bool) {
+	v, ok := r.json()
+	if !ok {
+		return nil, false
+	}
+
+	for _, p := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			if v, ok = node[p]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// json - decodes the body once, reporting a body that is not JSON
+func (r *Response) json() (interface{}, bool) {
+	r.t.Helper()
+
+	if !r.decodeOK {
+		if err := json.Unmarshal(r.Body, &r.decoded); err != nil {
+			r.t.Errorf("expected a JSON body, got %s", r.Body)
+			return nil, false
+		}
+		r.decodeOK = true
+	}
+
+	return r.decoded, true
+}
@@ -0,0 +1,153 @@
+package apptest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fakeTB - records the failures reported by the assertions instead of failing the test
+type fakeTB struct {
+	testing.TB
+	errors []string
+	fatals []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Fatalf(format string, args ...interface{}) {
+	f.fatals = append(f.fatals, fmt.Sprintf(format, args...))
+}
+
+// response - returns a response reporting to a fake test
+func response(a *App, code int, header http.Header, body string) (*Response, *fakeTB) {
+	tb := &fakeTB{TB: a.t}
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &Response{t: tb, app: a, Code: code, Header: header, Body: []byte(body)}, tb
+}
+
+func TestAssertions(t *testing.T) {
+	a := New(t, WithError("E_TEAPOT", http.StatusTeapot, "no %s here"))
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	body := `{"status":"pass","checks":[{"name":"db","latency":1.5,"count":2}]}`
+
+	tests := []struct {
+		name   string
+		expect func(*Response)
+		errors []string
+		fatals []string
+	}{
+		{"status", func(r *Response) { r.ExpectStatus(http.StatusOK) }, nil, nil},
+		{"wrong status", func(r *Response) { r.ExpectStatus(http.StatusCreated) },
+			[]string{"expected status 201, got 200 with body " + body}, nil},
+		{"header", func(r *Response) { r.ExpectHeader("Content-Type", "application/json") }, nil, nil},
+		{"wrong header", func(r *Response) { r.ExpectHeader("Content-Type", "text/plain") },
+			[]string{"expected header Content-Type `text/plain`, got `application/json`"}, nil},
+		{"body contains", func(r *Response) { r.ExpectBodyContains(`"name":"db"`) }, nil, nil},
+		{"body does not contain", func(r *Response) { r.ExpectBodyContains("fail") },
+			[]string{"expected body to contain `fail`, got " + body}, nil},
+		{"json", func(r *Response) {
+			r.ExpectJSON(map[string]interface{}{"status": "pass", "checks": []map[string]interface{}{{"name": "db", "latency": 1.5, "count": 2}}})
+		}, nil, nil},
+		{"different json", func(r *Response) { r.ExpectJSON(map[string]string{"status": "pass"}) },
+			[]string{`expected JSON body {"status":"pass"}, got ` + body}, nil},
+		{"json path", func(r *Response) {
+			r.ExpectJSONPath("status", "pass").ExpectJSONPath("checks.0.name", "db").
+				ExpectJSONPath("checks.0.count", 2).ExpectJSONPath("checks.0.latency", 1.5)
+		}, nil, nil},
+		{"different json path", func(r *Response) { r.ExpectJSONPath("checks.0.name", "cache") },
+			[]string{`expected checks.0.name to be "cache", got db`}, nil},
+		{"missing json path", func(r *Response) { r.ExpectJSONPath("checks.1.name", "db").ExpectJSONPath("status.code", 1) },
+			[]string{"expected JSON path checks.1.name in body " + body, "expected JSON path status.code in body " + body}, nil},
+		{"unknown error code", func(r *Response) { r.ExpectErrorCode("E_UNKNOWN") },
+			// the fake does not stop the test, the status is checked against the empty error
+			[]string{"expected status 0, got 200 with body " + body, "expected error E_UNKNOWN, got  with message ``"},
+			[]string{"apptest: error code E_UNKNOWN is not in the catalog"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, tb := response(a, http.StatusOK, header, body)
+			tt.expect(r)
+
+			if strings.Join(tb.errors, "\n") != strings.Join(tt.errors, "\n") {
+				t.Errorf("expected errors\n%s\ngot\n%s", strings.Join(tt.errors, "\n"), strings.Join(tb.errors, "\n"))
+			}
+			if strings.Join(tb.fatals, "\n") != strings.Join(tt.fatals, "\n") {
+				t.Errorf("expected fatal errors\n%s\ngot\n%s", strings.Join(tt.fatals, "\n"), strings.Join(tb.fatals, "\n"))
+			}
+		})
+	}
+}
+
+func TestAssertionsOnInvalidJSON(t *testing.T) {
+	a := New(t)
+
+	r, tb := response(a, http.StatusOK, nil, "not json")
+	r.ExpectJSON(map[string]string{}).ExpectJSONPath("status", "pass")
+	if _, ok := r.JSONPath("status"); ok {
+		t.Error("expected no value in a body that is not JSON")
+	}
+
+	want := []string{"expected a JSON body, got not json", "expected a JSON body, got not json",
+		"expected JSON path status in body not json", "expected a JSON body, got not json"}
+	if strings.Join(tb.errors, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected errors\n%s", strings.Join(tb.errors, "\n"))
+	}
+
+	var v map[string]string
+	r.DecodeJSON(&v)
+	if len(tb.fatals) != 1 || !strings.HasPrefix(tb.fatals[0], "apptest: failed to decode body not json:") {
+		t.Errorf("unexpected fatal errors %v", tb.fatals)
+	}
+}
+
+func TestErrorCodeAssertion(t *testing.T) {
+	a := New(t, WithError("E_TEAPOT", http.StatusTeapot, "no %s here"), WithError("E_GONE", http.StatusGone, "gone"))
+
+	tests := []struct {
+		name   string
+		code   int
+		body   string
+		errors []string
+	}{
+		{"matching", http.StatusTeapot, `{"code":"E_TEAPOT","msg":"no tea here"}`, nil},
+		{"other code", http.StatusTeapot, `{"code":"E_GONE","msg":"gone"}`,
+			[]string{"expected error E_TEAPOT, got E_GONE with message `gone`"}},
+		{"other status", http.StatusGone, `{"code":"E_TEAPOT","msg":"no tea here"}`,
+			[]string{`expected status 418, got 410 with body {"code":"E_TEAPOT","msg":"no tea here"}`}},
+		{"not json", http.StatusTeapot, "teapot",
+			[]string{"expected error E_TEAPOT, got a body that is not JSON: teapot"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, tb := response(a, tt.code, nil, tt.body)
+			r.ExpectErrorCode("E_TEAPOT")
+
+			if strings.Join(tb.errors, "\n") != strings.Join(tt.errors, "\n") || len(tb.fatals) != 0 {
+				t.Errorf("unexpected failures %v %v", tb.errors, tb.fatals)
+			}
+		})
+	}
+}
+
+func TestRequestBuildFailure(t *testing.T) {
+	a := New(t)
+	tb := &fakeTB{TB: t}
+	a.t = tb
+
+	a.POST("/echo").JSON(make(chan int)).Do()
+	if len(tb.fatals) == 0 || !strings.HasPrefix(tb.fatals[0], "apptest: failed to build the request: json: unsupported type") {
+		t.Errorf("expected the encoding failure to be reported, got %v", tb.fatals)
+	}
+	a.t = t
+}
@@ -0,0 +1,237 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"httpframwork/app"
+	"httpframwork/modules/config"
+	"httpframwork/modules/errorcache"
+	"httpframwork/modules/version"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+
+	commands map[string]command
+)
+
+func init() {
+	commands = map[string]command{
+		"serve":       {"start the http server (default)", serve},
+		"routes":      {"print the registered routes", routes},
+		"config":      {"check | print [--redacted]: validate or print the effective configuration", configCmd},
+		"errors":      {"list: print the error code catalog", errorsCmd},
+		"healthcheck": {"[--path /heartbeat] [--timeout 5s]: probe the local server, exits 1 when unhealthy", healthcheck},
+		"version":     {"print the version, commit, build time and go version", versionCmd},
+		"help":        {"print this help", help},
+	}
+}
+
+// Run executes the sub command found in args and returns the process exit code
+func Run(args []string) int {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command `%s`\n\n", name)
+		help(nil)
+		return 2
+	}
+
+	if err := cmd.run(args); err != nil {
+		fmt.Fprintln(stderr, name+": "+err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+// bootstrap builds the application shared by every command
+func bootstrap() (*app.Application, error) {
+	application, err := app.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the server: %v", err)
+	}
+
+	return application, nil
+}
+
+// serve starts the server and blocks until it stops
+func serve(args []string) error {
+	application, err := bootstrap()
+	if err != nil {
+		return err
+	}
+
+	if err = application.Run(); err != nil {
+		log.Println("server stopped with error: " + err.Error())
+	}
+
+	return err
+}
+
+// routes prints the route table
+func routes(args []string) error {
+	application, err := bootstrap()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPATH\tMETHODS")
+	for _, r := range application.RouteTable() {
+		path := r.Path
+		if r.Prefix {
+			path += "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, path, strings.Join(r.Method, ","))
+	}
+
+	return w.Flush()
+}
+
+// configCmd validates or prints the configuration
+func configCmd(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected sub command check or print")
+	}
+
+	switch args[0] {
+	case "check":
+		// bootstrapping decodes and validates every config layer
+		if _, err := bootstrap(); err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, "configuration is valid")
+		return nil
+	case "print":
+		flags := flag.NewFlagSet("config print", flag.ContinueOnError)
+		redacted := flags.Bool("redacted", false, "replace secret values")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		application, err := bootstrap()
+		if err != nil {
+			return err
+		}
+
+		store := config.GetInstance(application.Container)
+		store.Sources().Print(stdout, store.Current(), *redacted)
+		return nil
+	}
+
+	return fmt.Errorf("unknown sub command `%s`", args[0])
+}
+
+// errorsCmd prints the error catalog sorted by code
+func errorsCmd(args []string) error {
+	if len(args) == 0 || args[0] != "list" {
+		return fmt.Errorf("expected sub command list")
+	}
+
+	application, err := bootstrap()
+	if err != nil {
+		return err
+	}
+
+	all := errorcache.GetInstance(application.Container).GetAll()
+	codes := make([]string, 0, len(all))
+	for code := range all {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CODE\tSTATUS\tMESSAGE")
+	for _, code := range codes {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", code, all[code].Status, all[code].Message)
+	}
+
+	return w.Flush()
+}
+
+// healthcheck probes the running server through its local listener
+func healthcheck(args []string) error {
+	flags := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	path := flags.String("path", "/heartbeat", "path to probe")
+	timeout := flags.Duration("timeout", 5*time.Second, "probe timeout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	application, err := bootstrap()
+	if err != nil {
+		return err
+	}
+
+	baseURL, client, err := application.LocalClient(*timeout)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Get(baseURL + *path)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, _ := ioutil.ReadAll(res.Body)
+	fmt.Fprintln(stdout, strings.TrimSpace(string(body)))
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unhealthy, status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// versionCmd prints the build details
+func versionCmd(args []string) error {
+	info := version.Get()
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "version:\t%s\n", info.Version)
+	fmt.Fprintf(w, "commit:\t%s\n", info.Commit)
+	fmt.Fprintf(w, "modified:\t%t\n", info.Modified)
+	fmt.Fprintf(w, "build time:\t%s\n", info.BuildTime)
+	fmt.Fprintf(w, "go version:\t%s\n", info.GoVersion)
+
+	return w.Flush()
+}
+
+// help prints the available commands
+func help(args []string) error {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(stderr, "usage: %s <command> [arguments]\n\ncommands:\n", os.Args[0])
+	w := tabwriter.NewWriter(stderr, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\t%s\n", name, commands[name].usage)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"httpframwork/modules/constant"
+)
+
+// configure - writes config.yml with the keys under app appended to a minimal configuration,
+// and a small error catalog, then points CONFIG_PATH at them
+func configure(t *testing.T, yml string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	yml = fmt.Sprintf(`app:
+  name: cli
+  domain: http://localhost:8080
+  environment: test
+  app_log: %s
+  log_level: error
+  config:
+    reload_interval: 0s
+`, filepath.Join(dir, "logs")) + yml
+	catalog := `en:
+  not_found:
+    status: 404
+    msg: No %s here
+  bad_input:
+    status: 400
+    msg: Invalid input
+`
+	for name, content := range map[string]string{"config.yml": yml, "errors.yml": catalog} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv(constant.EnvConfigPath, dir)
+	t.Setenv("ERROR_LANG", "")
+}
+
+// run - runs the command and returns its exit code and output
+func run(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+
+	var out, errOut bytes.Buffer
+	stdout, stderr = &out, &errOut
+	defer func() {
+		stdout, stderr = os.Stdout, os.Stderr
+	}()
+
+	code := Run(args)
+
+	return code, out.String(), errOut.String()
+}
+
+// fields - returns the output lines with their columns separated by single spaces
+func fields(out string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+
+	return lines
+}
+
+func TestUnknownCommand(t *testing.T) {
+	code, out, errOut := run(t, "deploy")
+	if code != 2 || out != "" {
+		t.Errorf("expected exit code 2 and no output, got %d %q", code, out)
+	}
+	if !strings.HasPrefix(errOut, "unknown command `deploy`") || !strings.Contains(errOut, "healthcheck") {
+		t.Errorf("expected the error and the usage, got\n%s", errOut)
+	}
+
+	if code, _, errOut = run(t, "help"); code != 0 || !strings.Contains(errOut, "commands:") {
+		t.Errorf("expected the usage, got %d\n%s", code, errOut)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	code, out, _ := run(t, "version")
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+
+	lines := fields(out)
+	if len(lines) != 5 {
+		t.Fatalf("unexpected output\n%s", out)
+	}
+	for i, prefix := range []string{"version:", "commit:", "modified:", "build time:", "go version: go"} {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Errorf("expected `%s`, got `%s`", prefix, lines[i])
+		}
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	configure(t, "")
+
+	code, out, errOut := run(t, "routes")
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d\n%s", code, errOut)
+	}
+
+	lines := fields(out)
+	if lines[0] != "NAME PATH METHODS" {
+		t.Errorf("expected the header, got %s", lines[0])
+	}
+	for _, want := range []string{"livez /livez GET", "readyz /readyz GET"} {
+		if !contains(lines, want) {
+			t.Errorf("expected `%s` in\n%s", want, out)
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		yml    string
+		args   []string
+		code   int
+		out    []string
+		hidden []string
+		errOut string
+	}{
+		{"check", "", []string{"config", "check"}, 0, []string{"configuration is valid"}, nil, ""},
+		{"check invalid", "  listen:\n    port: 8443\n  ssl:\n    enabled: true\n    port: 8443\n",
+			[]string{"config", "check"}, 1, nil, nil, "app.listen.port and app.ssl.port both bind port 8443"},
+		{"print", "  database:\n    password: hunter2\n", []string{"config", "print"}, 0,
+			[]string{"app.database.password = hunter2 (", "app.name = cli ("}, nil, ""},
+		{"print redacted", "  database:\n    password: hunter2\n", []string{"config", "print", "--redacted"}, 0,
+			[]string{"app.database.password = [REDACTED] (", "app.name = cli ("}, []string{"hunter2"}, ""},
+		{"print unknown flag", "", []string{"config", "print", "--secrets"}, 1, nil, nil, "flag provided but not defined"},
+		{"no sub command", "", []string{"config"}, 1, nil, nil, "config: expected sub command check or print"},
+		{"unknown sub command", "", []string{"config", "edit"}, 1, nil, nil, "config: unknown sub command `edit`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configure(t, tt.yml)
+
+			code, out, errOut := run(t, tt.args...)
+			if code != tt.code {
+				t.Fatalf("expected exit code %d, got %d\n%s", tt.code, code, errOut)
+			}
+			for _, want := range tt.out {
+				if !strings.Contains(out, want) {
+					t.Errorf("expected `%s` in\n%s", want, out)
+				}
+			}
+			for _, secret := range tt.hidden {
+				if strings.Contains(out, secret) {
+					t.Errorf("`%s` printed in\n%s", secret, out)
+				}
+			}
+			if !strings.Contains(errOut, tt.errOut) {
+				t.Errorf("expected `%s` in\n%s", tt.errOut, errOut)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	configure(t, "")
+
+	code, out, errOut := run(t, "errors", "list")
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d\n%s", code, errOut)
+	}
+	want := []string{"CODE STATUS MESSAGE", "bad_input 400 Invalid input", "not_found 404 No %s here"}
+	if got := fields(out); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), out)
+	}
+
+	if code, _, errOut = run(t, "errors"); code != 1 || !strings.Contains(errOut, "errors: expected sub command list") {
+		t.Errorf("expected the missing sub command, got %d %s", code, errOut)
+	}
+}
+
+func TestHealthcheck(t *testing.T) {
+	// a live listener standing in for the running server
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/heartbeat", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":"pass"}`)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, `{"status":"fail"}`)
+	})
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	defer srv.Close()
+	live := ln.Addr().(*net.TCPAddr).Port
+
+	// a port nothing listens on
+	dead, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadPort := dead.Addr().(*net.TCPAddr).Port
+	dead.Close()
+
+	tests := []struct {
+		name   string
+		port   int
+		args   []string
+		code   int
+		out    string
+		errOut string
+	}{
+		{"healthy", live, nil, 0, `{"status":"pass"}`, ""},
+		{"unhealthy", live, []string{"--path", "/readyz"}, 1, `{"status":"fail"}`, "healthcheck: unhealthy, status 503"},
+		{"not listening", deadPort, []string{"--timeout", "1s"}, 1, "", "connection refused"},
+		{"invalid flag", live, []string{"--timeout", "soon"}, 1, "", "invalid value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configure(t, fmt.Sprintf("  listen:\n    host: 127.0.0.1\n    port: %d\n", tt.port))
+
+			code, out, errOut := run(t, append([]string{"healthcheck"}, tt.args...)...)
+			if code != tt.code {
+				t.Fatalf("expected exit code %d, got %d\n%s", tt.code, code, errOut)
+			}
+			if strings.TrimSpace(out) != tt.out {
+				t.Errorf("expected `%s`, got `%s`", tt.out, out)
+			}
+			if !strings.Contains(errOut, tt.errOut) {
+				t.Errorf("expected `%s` in\n%s", tt.errOut, errOut)
+			}
+		})
+	}
+}
+
+// contains - tells whether the lines hold want
+func contains(lines []string, want string) bool {
+	for _, line := range lines {
+		if line == want {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,217 @@
+package app
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/viper"
+	"httpframwork/modules/config"
+	"httpframwork/modules/constant"
+	"httpframwork/modules/container"
+)
+
+// immutableConfig - keys that are only applied at startup, changing them requires a restart
+var immutableConfig = []string{
+	"app.listen",
+	"app.ssl",
+	"app.server",
+	"app.upgrade",
+	constant.AppEnvironment,
+	constant.ConfigReloadInterval,
+	"app.metrics",
+	"app.tracing",
+	"app.scheduler",
+	"app.static",
+	// the admin token can be rotated without a restart
+	constant.AdminEnabled,
+	constant.AdminHost,
+	constant.AdminPort,
+	constant.AdminNetwork,
+	constant.AdminSocket,
+	constant.AdminSocketMode,
+	constant.AdminFDName,
+}
+
+// Initializes application configuration
+func (a *Application) initConfig() (err error) {
+	a.Config, a.configSources, err = a.loadConfig()
+	return
+}
+
+// loadConfig - reads the layered configuration files and environment overrides into a new config instance
+func (a *Application) loadConfig() (conf *viper.Viper, sources config.Sources, err error) {
+	path := os.Getenv(constant.EnvConfigPath)
+
+	conf = viper.New()
+	setDefaults(conf)
+
+	if a.options.Config != nil {
+		sources, err = config.LoadMap(conf, a.options.Config)
+		return
+	}
+
+	log.Println("Searching for application configuration file...")
+	if path == "" {
+		p, _ := filepath.Abs(filepath.Dir(os.Args[0]))
+		path = p + constant.DefaultConfigPath // look for config in the working directory
+		log.Println("Loading configs from default location...")
+	} else {
+		log.Printf("Loading configs from location: %s\n", path)
+	}
+
+	sources, files, err := config.Load(conf, path, "config")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if debug, _ := strconv.ParseBool(os.Getenv(constant.EnvConfigDebug)); debug {
+		sources.Print(os.Stdout, conf, true)
+	}
+
+	a.configFiles = files
+
+	return
+}
+
+// setDefaults - registers fallback values for optional config keys
+func setDefaults(conf *viper.Viper) {
+	conf.SetDefault(constant.AppLogLevel, constant.DefaultLogLevel)
+	conf.SetDefault(constant.CORSAllowedOrigins, constant.DefaultAllowedOrigins)
+	conf.SetDefault(constant.ConfigReloadInterval, constant.DefaultConfigReloadInterval)
+	conf.SetDefault(constant.ServerShutdownTimeout, constant.DefaultShutdownTimeout)
+	conf.SetDefault(constant.ServerReadTimeout, constant.DefaultReadTimeout)
+	conf.SetDefault(constant.ServerReadHeaderTimeout, constant.DefaultReadHeaderTimeout)
+	conf.SetDefault(constant.ServerWriteTimeout, constant.DefaultWriteTimeout)
+	conf.SetDefault(constant.ServerIdleTimeout, constant.DefaultIdleTimeout)
+	conf.SetDefault(constant.ServerMaxHeaderBytes, constant.DefaultMaxHeaderBytes)
+	conf.SetDefault(constant.ServerKeepAlivesEnabled, true)
+	conf.SetDefault(constant.HTTP2Enabled, true)
+	conf.SetDefault(constant.HTTP2MaxConcurrentStreams, constant.DefaultHTTP2MaxStreams)
+	conf.SetDefault(constant.HTTP2MaxReadFrameSize, constant.DefaultHTTP2MaxFrameSize)
+	conf.SetDefault(constant.ListenEnabled, true)
+	conf.SetDefault(constant.ListenNetwork, constant.DefaultListenNetwork)
+	conf.SetDefault(constant.ListenSocketMode, constant.DefaultSocketMode)
+	conf.SetDefault(constant.SSLNetwork, constant.DefaultListenNetwork)
+	conf.SetDefault(constant.SSLSocketMode, constant.DefaultSocketMode)
+	conf.SetDefault(constant.HSTSMaxAge, constant.DefaultHSTSMaxAge)
+	conf.SetDefault(constant.SSLReloadInterval, constant.DefaultSSLReloadInterval)
+	conf.SetDefault(constant.UpgradeEnabled, true)
+	conf.SetDefault(constant.UpgradeReadyTimeout, constant.DefaultUpgradeTimeout)
+	conf.SetDefault(constant.HealthTimeout, constant.DefaultHealthTimeout)
+	conf.SetDefault(constant.HealthCacheTTL, constant.DefaultHealthCacheTTL)
+	conf.SetDefault(constant.ConnectionsMode, constant.DefaultConnectionsMode)
+	conf.SetDefault(constant.ConnectionsQueueTimeout, constant.DefaultQueueTimeout)
+	conf.SetDefault(constant.ConnectionsMaxQueued, constant.DefaultMaxQueued)
+	conf.SetDefault(constant.MetricsEnabled, true)
+	conf.SetDefault(constant.MetricsPath, constant.DefaultMetricsPath)
+	conf.SetDefault(constant.TracingSampleRatio, 1.0)
+	conf.SetDefault(constant.TracingExporter, constant.DefaultTracingExporter)
+	conf.SetDefault(constant.TracingFile, constant.DefaultTracingFile)
+	conf.SetDefault(constant.TracingEndpoint, constant.DefaultTracingEndpoint)
+	conf.SetDefault(constant.TracingFlushInterval, constant.DefaultTracingInterval)
+	conf.SetDefault(constant.TracingBatchSize, constant.DefaultTracingBatchSize)
+	conf.SetDefault(constant.TracingTimeout, constant.DefaultTracingTimeout)
+	conf.SetDefault(constant.RateLimitBurst, constant.DefaultRateLimitBurst)
+	conf.SetDefault(constant.StaticPath, constant.DefaultStaticPath)
+	conf.SetDefault(constant.StaticDir, constant.DefaultStaticDir)
+	conf.SetDefault(constant.StaticSPA, true)
+	conf.SetDefault(constant.SchedulerEnabled, true)
+	conf.SetDefault(constant.LogCleanupSpec, constant.DefaultLogCleanupSpec)
+	conf.SetDefault(constant.AdminHost, constant.DefaultAdminHost)
+	conf.SetDefault(constant.AdminPort, constant.DefaultAdminPort)
+	conf.SetDefault(constant.AdminNetwork, constant.DefaultListenNetwork)
+	conf.SetDefault(constant.AdminSocketMode, constant.DefaultSocketMode)
+}
+
+// initConfigStore - shares the loaded config through the container so components can follow reloads
+func (a *Application) initConfigStore(cont *container.Container) {
+	config.GetInstance(cont).
+		Immutable(immutableConfig...).
+		Init(a.Config, a.configSources)
+}
+
+// CurrentConfig - returns the config in effect, unlike Config it follows reloads. It must not be modified.
+func (a *Application) CurrentConfig() *viper.Viper {
+	return config.GetInstance(a.Container).Current()
+}
+
+// ReloadConfig - reads the configuration files again and swaps in the new config when it is valid.
+// The file watch and SIGHUP may reload at the same time, reloads run one after the other.
+func (a *Application) ReloadConfig() {
+	a.reload.Lock()
+	defer a.reload.Unlock()
+
+	conf, sources, err := a.loadConfig()
+	if err != nil {
+		log.Printf("Config reload failed with error `%v`, keeping the current config\n", err)
+		return
+	}
+
+	changes, err := config.GetInstance(a.Container).Swap(conf, sources)
+	if err != nil {
+		log.Printf("Config reload failed with error `%v`, keeping the current config\n", err)
+		return
+	}
+
+	for _, c := range changes {
+		log.Printf("Config key %s changed\n", c.Key)
+	}
+}
+
+// watchConfig - reloads the config whenever one of its files is modified
+func (a *Application) watchConfig(interval time.Duration) {
+	if interval <= 0 || a.stopWatch != nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	a.stopWatch = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		modTime := latestModTime(a.watchedFiles())
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if t := latestModTime(a.watchedFiles()); t.After(modTime) {
+					modTime = t
+					a.ReloadConfig()
+				}
+			}
+		}
+	}()
+}
+
+// watchedFiles - returns the config files read by the last load
+func (a *Application) watchedFiles() []string {
+	a.reload.Lock()
+	defer a.reload.Unlock()
+
+	return a.configFiles
+}
+
+// stopConfigWatch - ends the config file watch
+func (a *Application) stopConfigWatch() {
+	if a.stopWatch != nil {
+		close(a.stopWatch)
+		a.stopWatch = nil
+	}
+}
+
+// latestModTime - returns the most recent modification time of the files
+func latestModTime(files []string) (t time.Time) {
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil && info.ModTime().After(t) {
+			t = info.ModTime()
+		}
+	}
+
+	return
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"httpframwork/modules/constant"
+	"httpframwork/modules/errorcache"
+	"httpframwork/modules/features"
+)
+
+// writeConfig - writes config.yml with the reloadable keys under test
+func writeConfig(t *testing.T, dir, logLevel string, rate int, beta bool) {
+	t.Helper()
+
+	yml := fmt.Sprintf(`app:
+  name: reload
+  domain: http://localhost:8080
+  environment: test
+  app_log: %s
+  log_level: %s
+  config:
+    reload_interval: 0s
+  ratelimit:
+    rate: %d
+    burst: 1
+  features:
+    beta: %v
+`, filepath.Join(dir, "logs"), logLevel, rate, beta)
+
+	if err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte(yml), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "error", 0, false)
+	t.Setenv(constant.EnvConfigPath, dir)
+
+	a, err := NewWithOptions(Options{Errors: errorcache.ErrorsConfig{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Shutdown(context.Background())
+
+	handler := a.Handler()
+	get := func(remote string) int {
+		req := httptest.NewRequest(http.MethodGet, "/livez", nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	flags := features.GetInstance(a.Container)
+	if flags.Enabled("beta") {
+		t.Fatal("beta must start off")
+	}
+
+	writeConfig(t, dir, "warn", 1, true)
+
+	// the file watch and SIGHUP reload while requests are served
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			a.ReloadConfig()
+		}()
+		go func(i int) {
+			defer wg.Done()
+			get(fmt.Sprintf("10.0.0.%d:1000", i))
+			a.CurrentConfig().GetString(constant.AppLogLevel)
+			_ = a.Settings().LogLevel
+		}(i)
+	}
+	wg.Wait()
+
+	if got := a.CurrentConfig().GetString(constant.AppLogLevel); got != "warn" {
+		t.Errorf("expected the reloaded log level, got %s", got)
+	}
+	if got := a.Settings().LogLevel; got != "warn" {
+		t.Errorf("expected the typed settings to follow, got %s", got)
+	}
+	if got := a.Config.GetString(constant.AppLogLevel); got != "error" {
+		t.Errorf("expected Config to keep the startup value, got %s", got)
+	}
+	if a.Log.GetLevel().String() != "warning" {
+		t.Errorf("expected the logger to follow, got %s", a.Log.GetLevel())
+	}
+	if !flags.Enabled("beta") {
+		t.Error("expected the beta flag to follow the reload")
+	}
+	if first, second := get("10.0.1.1:1000"), get("10.0.1.1:1000"); first != http.StatusOK || second != http.StatusTooManyRequests {
+		t.Errorf("expected the reloaded rate limit to apply, got %d then %d", first, second)
+	}
+}
@@ -0,0 +1,268 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpframwork/modules/constant"
+	"httpframwork/modules/systemd"
+	"httpframwork/modules/upgrade"
+)
+
+// listenKeys - config keys describing one listener
+type listenKeys struct {
+	name       string
+	host       string
+	port       string
+	network    string
+	socket     string
+	socketMode string
+	fdName     string
+	// defaultPort is bound when no port is configured, empty uses the port of app.domain
+	defaultPort string
+}
+
+var (
+	plainListener = listenKeys{
+		name:       "http",
+		host:       constant.ListenHost,
+		port:       constant.ListenPort,
+		network:    constant.ListenNetwork,
+		socket:     constant.ListenSocket,
+		socketMode: constant.ListenSocketMode,
+		fdName:     constant.ListenFDName,
+	}
+
+	tlsListener = listenKeys{
+		name:       "https",
+		host:       constant.SSLHost,
+		port:       constant.SSLPort,
+		network:    constant.SSLNetwork,
+		socket:     constant.SSLSocket,
+		socketMode: constant.SSLSocketMode,
+		fdName:     constant.SSLFDName,
+		// the plain listener owns the port of app.domain
+		defaultPort: constant.DefaultSSLPort,
+	}
+
+	adminListener = listenKeys{
+		name:       "admin",
+		host:       constant.AdminHost,
+		port:       constant.AdminPort,
+		network:    constant.AdminNetwork,
+		socket:     constant.AdminSocket,
+		socketMode: constant.AdminSocketMode,
+		fdName:     constant.AdminFDName,
+	}
+)
+
+// listenAddress - resolves the tcp address to bind from the given config keys.
+// When no port is configured the default port of the listener, or else the port of app.domain, is used.
+func (a *Application) listenAddress(keys listenKeys) (address string, err error) {
+	port := a.Config.GetString(keys.port)
+	if port == "" {
+		port = keys.defaultPort
+	}
+	if port == "" {
+		var pURL *url.URL
+		if pURL, err = url.ParseRequestURI(a.Domain); err != nil {
+			return
+		}
+		port = pURL.Port()
+	}
+
+	return net.JoinHostPort(a.Config.GetString(keys.host), port), nil
+}
+
+// listen - opens the tcp, unix socket or inherited listener described by the given config keys
+func (a *Application) listen(keys listenKeys) (ln net.Listener, err error) {
+	network := a.Config.GetString(keys.network)
+
+	// listeners handed over by the previous process take precedence
+	if ln = upgrade.Take(keys.name); ln != nil {
+		log.Printf("Listening on %s (inherited)\n", ln.Addr())
+		return
+	}
+
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		var address string
+		if address, err = a.listenAddress(keys); err != nil {
+			return
+		}
+		ln, err = net.Listen(network, address)
+	case "unix":
+		ln, err = a.listenUnix(keys)
+	case "systemd":
+		ln, err = systemd.Take(a.Config.GetString(keys.fdName))
+	default:
+		return nil, fmt.Errorf("unsupported listen network `%s`", network)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to start listener with error `%v`", err)
+	}
+
+	log.Printf("Listening on %s (%s)\n", ln.Addr(), network)
+
+	return
+}
+
+// listenUnix - opens a unix socket, removing a stale socket file left by a crashed process
+func (a *Application) listenUnix(keys listenKeys) (ln net.Listener, err error) {
+	path := a.Config.GetString(keys.socket)
+	if path == "" {
+		return nil, fmt.Errorf("%s is required for unix listeners", keys.socket)
+	}
+
+	mode, err := strconv.ParseUint(a.Config.GetString(keys.socketMode), 8, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", keys.socketMode, err)
+	}
+
+	if err = removeStaleSocket(path); err != nil {
+		return
+	}
+
+	if ln, err = net.Listen("unix", path); err != nil {
+		return
+	}
+
+	if err = os.Chmod(path, os.FileMode(mode)); err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	return
+}
+
+// removeStaleSocket - deletes the socket file when no process is accepting on it
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+
+	log.Printf("Removing stale socket %s\n", path)
+
+	return os.Remove(path)
+}
+
+// unwrap - returns the socket below the connection limits
+func unwrap(ln net.Listener) net.Listener {
+	if w, ok := ln.(interface{ Unwrap() net.Listener }); ok {
+		return w.Unwrap()
+	}
+
+	return ln
+}
+
+// listenerFile - returns a duplicate of the listening socket descriptor to hand over
+func listenerFile(ln net.Listener) (*os.File, error) {
+	switch l := unwrap(ln).(type) {
+	case *net.TCPListener:
+		return l.File()
+	case *net.UnixListener:
+		return l.File()
+	}
+
+	return nil, fmt.Errorf("listener %s can not be handed over", ln.Addr())
+}
+
+// upgrade - starts the current binary with the listeners and waits until it serves them
+func (a *Application) upgrade() (err error) {
+	path, err := os.Executable()
+	if err != nil {
+		return
+	}
+
+	listeners := make([]upgrade.Listener, 0, len(a.endpoints))
+	defer func() {
+		for _, l := range listeners {
+			l.File.Close()
+		}
+	}()
+
+	for _, e := range a.endpoints {
+		var f *os.File
+		if f, err = listenerFile(e.listener); err != nil {
+			return
+		}
+		listeners = append(listeners, upgrade.Listener{Name: e.name, File: f})
+	}
+
+	process, err := upgrade.Handoff(path, os.Args[1:], listeners, a.Config.GetDuration(constant.UpgradeReadyTimeout))
+	if err != nil {
+		return
+	}
+
+	// the socket files now belong to the new process, a failed handoff keeps removing them on shutdown
+	for _, e := range a.endpoints {
+		if l, ok := unwrap(e.listener).(*net.UnixListener); ok {
+			l.SetUnlinkOnClose(false)
+		}
+	}
+
+	log.Printf("Handed over listeners to process %d\n", process.Pid)
+
+	return
+}
+
+// LocalClient - returns the base URL and a client reaching this application from the same host,
+// preferring the plain listener over the TLS one
+func (a *Application) LocalClient(timeout time.Duration) (baseURL string, client *http.Client, err error) {
+	keys, scheme := plainListener, "http"
+	if !a.Config.GetBool(constant.ListenEnabled) || a.Config.GetBool(constant.ListenRedirect) {
+		keys, scheme = tlsListener, "https"
+	}
+
+	transport := &http.Transport{
+		// the certificate is issued for the public name, not for the loopback address
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	client = &http.Client{Timeout: timeout, Transport: transport}
+
+	switch network := a.Config.GetString(keys.network); network {
+	case "unix":
+		socket := a.Config.GetString(keys.socket)
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return new(net.Dialer).DialContext(ctx, "unix", socket)
+		}
+		return scheme + "://localhost", client, nil
+	case "systemd":
+		// the address is owned by the service manager, go through the public domain
+		return strings.TrimRight(a.Domain, "/"), client, nil
+	}
+
+	address, err := a.listenAddress(keys)
+	if err != nil {
+		return
+	}
+
+	host, port, _ := net.SplitHostPort(address)
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+
+	return scheme + "://" + net.JoinHostPort(host, port), client, nil
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"httpframwork/modules/constant"
+)
+
+func TestListenerFileKeepsSocketCleanup(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := listenerFile(ln)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// the handoff may still fail, only a successful one hands the socket file over
+	ln.Close()
+	if _, err = os.Stat(socket); !os.IsNotExist(err) {
+		t.Errorf("expected the socket file to be removed, got %v", err)
+	}
+}
+
+func TestRemoveStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+
+	// a socket left behind by a process that did not close it
+	stale := filepath.Join(dir, "stale.sock")
+	ln, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+
+	live := filepath.Join(dir, "live.sock")
+	if ln, err = net.Listen("unix", live); err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	file := filepath.Join(dir, "file")
+	os.WriteFile(file, []byte("data"), 0600)
+
+	tests := []struct {
+		name, path, problem string
+		exists              bool
+	}{
+		{"missing", filepath.Join(dir, "missing.sock"), "", false},
+		{"stale", stale, "", false},
+		{"live", live, "is in use by another process", true},
+		{"not a socket", file, "exists and is not a socket", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := removeStaleSocket(tt.path)
+			switch {
+			case tt.problem == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.problem != "" && (err == nil || !strings.Contains(err.Error(), tt.problem)):
+				t.Fatalf("expected `%s`, got %v", tt.problem, err)
+			}
+			if _, err = os.Stat(tt.path); os.IsNotExist(err) == tt.exists {
+				t.Errorf("expected the file to exist %v, got %v", tt.exists, err)
+			}
+		})
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name, socket, mode, problem string
+		want                        os.FileMode
+	}{
+		{"default mode", filepath.Join(dir, "default.sock"), "", "", 0660},
+		{"configured mode", filepath.Join(dir, "open.sock"), "0666", "", 0666},
+		{"private mode", filepath.Join(dir, "private.sock"), "600", "", 0600},
+		{"no path", "", "", "app.listen.socket is required for unix listeners", 0},
+		{"invalid mode", filepath.Join(dir, "invalid.sock"), "rw-rw----", "invalid app.listen.socket_mode", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := map[string]interface{}{constant.ListenSocket: tt.socket}
+			if tt.mode != "" {
+				settings[constant.ListenSocketMode] = tt.mode
+			}
+
+			ln, err := tlsApp(settings).listenUnix(plainListener)
+			if tt.problem != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.problem) {
+					t.Fatalf("expected `%s`, got %v", tt.problem, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+
+			info, err := os.Stat(tt.socket)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != tt.want {
+				t.Errorf("expected a socket with mode %v, got %v", tt.want, info.Mode())
+			}
+		})
+	}
+
+	// a live socket is never taken over
+	live := filepath.Join(dir, "live.sock")
+	ln, err := tlsApp(map[string]interface{}{constant.ListenSocket: live}).listenUnix(plainListener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if _, err = tlsApp(map[string]interface{}{constant.ListenSocket: live}).listenUnix(plainListener); err == nil {
+		t.Error("expected the socket in use to be refused")
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AdminToken - rejects requests without the bearer token returned by token.
+// The token is looked up on every request so it can be rotated by a config reload.
+func AdminToken(token func() string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			expected := token()
+			given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+			if expected == "" || subtle.ConstantTimeCompare([]byte(given), []byte(expected)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHSTS(t *testing.T) {
+	tests := []struct {
+		maxAge     int
+		subdomains bool
+		want       string
+	}{
+		{31536000, false, "max-age=31536000"},
+		{600, true, "max-age=600; includeSubDomains"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			})
+
+			rec := httptest.NewRecorder()
+			HSTS(tt.maxAge, tt.subdomains)(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if got := rec.Header().Get("Strict-Transport-Security"); got != tt.want {
+				t.Errorf("expected `%s`, got `%s`", tt.want, got)
+			}
+			if rec.Code != http.StatusTeapot {
+				t.Errorf("expected the response of the next handler, got %d", rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"httpframwork/modules/metrics"
+)
+
+// Metrics - counts requests and observes their latency and response size per route name,
+// method and status class
+func Metrics(reg *metrics.Registry) func(http.Handler) http.Handler {
+	labels := []string{"route", "method", "code"}
+	requests := reg.NewCounter("http_requests_total", "Number of HTTP requests handled.", labels...)
+	inFlight := reg.NewGauge("http_requests_in_flight", "Number of HTTP requests being handled.", "route", "method")
+	latency := reg.NewHistogram("http_request_duration_seconds", "Latency of HTTP requests.", metrics.DefBuckets, labels...)
+	size := reg.NewHistogram("http_response_size_bytes", "Size of HTTP responses.", metrics.SizeBuckets, labels...)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := "unnamed"
+			if cur := mux.CurrentRoute(r); cur != nil && cur.GetName() != "" {
+				route = cur.GetName()
+			}
+
+			gauge := inFlight.With(route, r.Method)
+			gauge.Inc()
+			defer gauge.Dec()
+
+			rec := &responseRecorder{ResponseWriter: w}
+			start := time.Now()
+			defer func() {
+				if rec.status == 0 {
+					rec.status = http.StatusOK
+				}
+				code := strconv.Itoa(rec.status/100) + "xx"
+
+				requests.With(route, r.Method, code).Inc()
+				latency.With(route, r.Method, code).Observe(time.Since(start).Seconds())
+				size.With(route, r.Method, code).Observe(float64(rec.size))
+			}()
+
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
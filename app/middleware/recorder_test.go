@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"httpframwork/modules/metrics"
+)
+
+// newRegistry - returns a metrics registry as registered in the container
+func newRegistry() *metrics.Registry {
+	return metrics.GetRegistry()[0].Value.(*metrics.Registry)
+}
+
+func TestRecorderFlushes(t *testing.T) {
+	reg := newRegistry()
+	handler := Metrics(reg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("event"))
+		w.(http.Flusher).Flush()
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	if !rec.Flushed {
+		t.Error("flush did not reach the response writer")
+	}
+}
+
+func TestRecorderHijacks(t *testing.T) {
+	reg := newRegistry()
+	server := httptest.NewServer(Metrics(reg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\nhello\n")
+		buf.Flush()
+	})))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %d", res.StatusCode)
+	}
+	if line, _ := reader.ReadString('\n'); line != "hello\n" {
+		t.Errorf("expected the hijacked connection to carry `hello`, got `%s`", line)
+	}
+
+	var out strings.Builder
+	reg.Write(&out)
+	if want := `http_requests_total{route="unnamed",method="GET",code="1xx"} 1`; !strings.Contains(out.String(), want) {
+		t.Errorf("expected `%s` in\n%s", want, out.String())
+	}
+}
+
+func TestRecorderWithoutHijacker(t *testing.T) {
+	rec := &responseRecorder{ResponseWriter: httptest.NewRecorder()}
+	if _, _, err := rec.Hijack(); err == nil {
+		t.Error("expected an error from a writer that can not be hijacked")
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"httpframwork/modules/tracing"
+)
+
+// Tracing - starts a server span named after the route, continuing the trace of the caller,
+// and returns its traceparent to the client
+func Tracing(tracer *tracing.Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name, template := "unnamed", ""
+			if cur := mux.CurrentRoute(r); cur != nil {
+				if cur.GetName() != "" {
+					name = cur.GetName()
+				}
+				template, _ = cur.GetPathTemplate()
+			}
+
+			span := tracer.StartServer(r, name)
+			defer span.Finish()
+
+			span.SetAttribute("http.request.method", r.Method)
+			span.SetAttribute("http.route", template)
+			span.SetAttribute("url.path", r.URL.Path)
+			span.SetAttribute("client.address", r.RemoteAddr)
+			span.SetAttribute("user_agent.original", r.UserAgent())
+
+			span.Inject(w.Header())
+
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r.WithContext(tracing.ContextWithSpan(r.Context(), span)))
+
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			span.SetAttribute("http.response.status_code", rec.status)
+			if rec.status >= http.StatusInternalServerError {
+				span.SetError(fmt.Errorf("%d %s", rec.status, http.StatusText(rec.status)))
+			}
+		})
+	}
+}
@@ -0,0 +1,482 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+	"httpframwork/modules/config"
+	"httpframwork/modules/connlimit"
+	"httpframwork/modules/constant"
+	"httpframwork/modules/errorcache"
+	"httpframwork/modules/features"
+	"httpframwork/modules/health"
+	"httpframwork/modules/logger"
+	"httpframwork/modules/metrics"
+	"httpframwork/modules/ratelimit"
+	"httpframwork/modules/scheduler"
+	"httpframwork/modules/tracing"
+)
+
+type (
+	// Module is a subsystem bootstrapped and run by the application
+	Module interface {
+		// Name identifies the module, it is used to declare dependencies
+		Name() string
+		// Dependencies lists the modules that must be initialized and started first
+		Dependencies() []string
+		// Init prepares the module while the application is created
+		Init(app *Application) error
+		// Start runs the module, ctx is cancelled when the application stops
+		Start(ctx context.Context) error
+		// Stop releases the module, ctx carries the shutdown deadline
+		Stop(ctx context.Context) error
+	}
+
+	// BaseModule provides no-op lifecycle hooks to embed in modules
+	BaseModule struct{}
+
+	// errorcacheModule loads the error catalog into the container
+	errorcacheModule struct {
+		BaseModule
+	}
+
+	// healthModule configures the health registry and follows config reloads
+	healthModule struct {
+		BaseModule
+	}
+
+	// rateLimitModule applies the per-client request rate and follows config reloads
+	rateLimitModule struct {
+		BaseModule
+	}
+
+	// featuresModule loads the feature flags and follows config reloads
+	featuresModule struct {
+		BaseModule
+	}
+
+	// metricsModule registers the Go runtime metrics
+	metricsModule struct {
+		BaseModule
+	}
+
+	// tracingModule exports the spans of the sampled requests
+	tracingModule struct {
+		BaseModule
+		tracer   *tracing.Tracer
+		interval time.Duration
+	}
+
+	// connectionsModule applies the connection limits and reports the counts
+	connectionsModule struct {
+		BaseModule
+	}
+
+	// schedulerModule runs the registered jobs while the application runs
+	schedulerModule struct {
+		BaseModule
+		scheduler *scheduler.Scheduler
+	}
+
+	// configModule watches the configuration files while the application runs
+	configModule struct {
+		BaseModule
+		app *Application
+	}
+)
+
+// Dependencies - no dependencies
+func (BaseModule) Dependencies() []string { return nil }
+
+// Init - nothing to prepare
+func (BaseModule) Init(*Application) error { return nil }
+
+// Start - nothing to run
+func (BaseModule) Start(context.Context) error { return nil }
+
+// Stop - nothing to release
+func (BaseModule) Stop(context.Context) error { return nil }
+
+func (errorcacheModule) Name() string { return "errorcache" }
+
+func (errorcacheModule) Init(a *Application) (err error) {
+	if a.options.Errors != nil {
+		// catalogs given in code stay out of the global container and may be empty
+		errorcache.GetInstance(a.Container).Set(a.options.Errors)
+		return
+	}
+
+	if err = errorcache.PopulateErrorCodes(a.Container); err != nil {
+		return
+	}
+
+	cache := errorcache.GetInstance(a.Container)
+	health.GetInstance(a.Container).Register(health.Check{
+		Name:     "errorcache",
+		Critical: true,
+		Liveness: true,
+		Func: func(context.Context) error {
+			if len(cache.GetAll()) == 0 {
+				return errors.New("error catalog is empty")
+			}
+			return nil
+		},
+	})
+
+	return
+}
+
+func (healthModule) Name() string { return "health" }
+
+func (healthModule) Init(a *Application) error {
+	registry := health.GetInstance(a.Container).
+		Configure(a.Config.GetDuration(constant.HealthTimeout), a.Config.GetDuration(constant.HealthCacheTTL))
+
+	config.GetInstance(a.Container).Subscribe("app.health", func(_, next *viper.Viper, _ []config.Change) {
+		registry.Configure(next.GetDuration(constant.HealthTimeout), next.GetDuration(constant.HealthCacheTTL))
+	})
+
+	return nil
+}
+
+func (rateLimitModule) Name() string { return "ratelimit" }
+
+func (rateLimitModule) Init(a *Application) error {
+	limiter := ratelimit.GetInstance(a.Container).
+		Configure(a.Config.GetFloat64(constant.RateLimitRate), a.Config.GetInt(constant.RateLimitBurst))
+
+	config.GetInstance(a.Container).Subscribe("app.ratelimit", func(_, next *viper.Viper, _ []config.Change) {
+		limiter.Configure(next.GetFloat64(constant.RateLimitRate), next.GetInt(constant.RateLimitBurst))
+	})
+
+	return nil
+}
+
+func (featuresModule) Name() string { return "features" }
+
+func (featuresModule) Init(a *Application) (err error) {
+	flags := features.GetInstance(a.Container)
+
+	var initial map[string]bool
+	if err = a.Config.UnmarshalKey(constant.Features, &initial); err != nil {
+		return
+	}
+	flags.Set(initial)
+
+	config.GetInstance(a.Container).Subscribe(constant.Features, func(_, next *viper.Viper, _ []config.Change) {
+		var reloaded map[string]bool
+		if err := next.UnmarshalKey(constant.Features, &reloaded); err == nil {
+			flags.Set(reloaded)
+			log.Printf("Feature flags on: %s\n", strings.Join(flags.List(), ", "))
+		}
+	})
+
+	return
+}
+
+func (m *configModule) Name() string { return "config" }
+
+func (m *configModule) Init(a *Application) error {
+	m.app = a
+	return nil
+}
+
+func (m *configModule) Start(context.Context) error {
+	m.app.watchConfig(m.app.Config.GetDuration(constant.ConfigReloadInterval))
+	return nil
+}
+
+func (m *configModule) Stop(context.Context) error {
+	m.app.stopConfigWatch()
+	return nil
+}
+
+func (metricsModule) Name() string { return "metrics" }
+
+func (metricsModule) Init(a *Application) error {
+	metrics.RegisterRuntime(metrics.GetInstance(a.Container))
+	return nil
+}
+
+func (m *tracingModule) Name() string { return "tracing" }
+
+func (m *tracingModule) Init(a *Application) error {
+	if !a.Config.GetBool(constant.TracingEnabled) {
+		return nil
+	}
+
+	var exporter tracing.Exporter
+	switch a.Config.GetString(constant.TracingExporter) {
+	case "file":
+		exporter = &tracing.FileExporter{Path: a.Config.GetString(constant.TracingFile)}
+	case "otlp":
+		exporter = &tracing.HTTPExporter{
+			Endpoint: a.Config.GetString(constant.TracingEndpoint),
+			Client:   &http.Client{Timeout: a.Config.GetDuration(constant.TracingTimeout)},
+		}
+	}
+
+	service := a.Config.GetString(constant.TracingServiceName)
+	if service == "" {
+		service = a.Config.GetString(constant.AppName)
+	}
+
+	m.tracer = tracing.GetInstance(a.Container).Configure(service,
+		a.Config.GetFloat64(constant.TracingSampleRatio), a.Config.GetInt(constant.TracingBatchSize), exporter)
+	m.interval = a.Config.GetDuration(constant.TracingFlushInterval)
+
+	return nil
+}
+
+func (m *tracingModule) Start(ctx context.Context) error {
+	if m.tracer != nil {
+		go m.tracer.Run(ctx, m.interval)
+	}
+	return nil
+}
+
+func (m *tracingModule) Stop(ctx context.Context) error {
+	if m.tracer == nil {
+		return nil
+	}
+	return m.tracer.Flush(ctx)
+}
+
+func (connectionsModule) Name() string { return "connections" }
+
+func (connectionsModule) Dependencies() []string { return []string{"metrics"} }
+
+func (connectionsModule) Init(a *Application) error {
+	limiter := connlimit.GetInstance(a.Container).Configure(
+		a.Config.GetInt(constant.ConnectionsMax),
+		a.Config.GetInt(constant.ConnectionsPerIP),
+		a.Config.GetString(constant.ConnectionsMode),
+		a.Config.GetDuration(constant.ConnectionsQueueTimeout),
+		a.Config.GetInt(constant.ConnectionsMaxQueued),
+	)
+
+	reg := metrics.GetInstance(a.Container)
+	reg.NewGaugeFunc("http_connections_active", "Number of open connections on the public listeners.", func() float64 {
+		return float64(limiter.Counts().Active)
+	})
+	reg.NewGaugeFunc("http_connections_queued", "Number of connections waiting for a free slot.", func() float64 {
+		return float64(limiter.Counts().Queued)
+	})
+	reg.NewCounterFunc("http_connections_accepted_total", "Number of connections admitted.", func() float64 {
+		return float64(limiter.Counts().Accepted)
+	})
+	reg.NewCounterFunc("http_connections_rejected_total", "Number of connections closed for exceeding a limit.", func() float64 {
+		return float64(limiter.Counts().Rejected)
+	})
+
+	a.AdminRoutes = append(a.AdminRoutes, AppRoutes{}.New("connections", "/connections", []string{http.MethodGet},
+		func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, limiter.Stats())
+		}))
+
+	return nil
+}
+
+func (m *schedulerModule) Name() string { return "scheduler" }
+
+func (m *schedulerModule) Init(a *Application) (err error) {
+	if !a.Config.GetBool(constant.SchedulerEnabled) {
+		return
+	}
+
+	location, err := scheduler.LoadLocation(a.Config.GetString(constant.SchedulerTimezone))
+	if err != nil {
+		return
+	}
+
+	logDir := a.Config.GetString(constant.AppLogFolder)
+	m.scheduler = scheduler.GetInstance(a.Container).Configure(location, logDir)
+
+	if retention := a.Config.GetDuration(constant.LogCleanupRetention); retention > 0 {
+		err = m.scheduler.Register(scheduler.Job{
+			Name:   "log_cleanup",
+			Spec:   a.Config.GetString(constant.LogCleanupSpec),
+			Jitter: time.Minute,
+			Func: func(_ context.Context, l *logs.Log) error {
+				removed, err := logs.Cleanup(logDir, retention)
+				l.Print("Removed ", removed)
+				return err
+			},
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	a.AdminRoutes = append(a.AdminRoutes,
+		AppRoutes{}.New("jobs", "/jobs", []string{http.MethodGet}, func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, m.scheduler.List())
+		}),
+		AppRoutes{}.New("job-run", "/jobs/{name}/run", []string{http.MethodPost}, func(w http.ResponseWriter, r *http.Request) {
+			switch err := m.scheduler.Trigger(mux.Vars(r)["name"]); err {
+			case nil:
+				writeJSON(w, http.StatusAccepted, map[string]string{"status": "queued"})
+			case scheduler.ErrUnknownJob:
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			case scheduler.ErrNotRunning:
+				writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+			default:
+				writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			}
+		}),
+	)
+
+	return
+}
+
+func (m *schedulerModule) Start(ctx context.Context) error {
+	if m.scheduler != nil {
+		m.scheduler.Start(ctx)
+	}
+	return nil
+}
+
+func (m *schedulerModule) Stop(ctx context.Context) error {
+	if m.scheduler == nil {
+		return nil
+	}
+	return m.scheduler.Stop(ctx)
+}
+
+// defaultModules - modules every application runs
+func defaultModules() []Module {
+	return []Module{
+		&configModule{},
+		healthModule{},
+		rateLimitModule{},
+		featuresModule{},
+		metricsModule{},
+		&tracingModule{},
+		connectionsModule{},
+		&schedulerModule{},
+		errorcacheModule{},
+	}
+}
+
+// Module - returns the registered module with the name, nil when there is none
+func (a *Application) Module(name string) Module {
+	for _, m := range a.modules {
+		if m.Name() == name {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// initModules - orders the modules by their dependencies and initializes them
+func (a *Application) initModules(modules []Module) (err error) {
+	if a.modules, err = sortModules(modules); err != nil {
+		return
+	}
+
+	for _, m := range a.modules {
+		if err = m.Init(a); err != nil {
+			return fmt.Errorf("module %s failed to init: %v", m.Name(), err)
+		}
+	}
+
+	return
+}
+
+// startModules - starts the modules in dependency order, the started ones are stopped again on failure
+func (a *Application) startModules(ctx context.Context) (err error) {
+	for i, m := range a.modules {
+		if err = m.Start(ctx); err != nil {
+			err = fmt.Errorf("module %s failed to start: %v", m.Name(), err)
+
+			a.started = a.modules[:i]
+			stopCtx, cancel := context.WithTimeout(context.Background(), a.Config.GetDuration(constant.ServerShutdownTimeout))
+			defer cancel()
+			a.stopModules(stopCtx)
+
+			return
+		}
+	}
+	a.started = a.modules
+
+	return
+}
+
+// stopModules - stops the started modules in reverse order, every module is stopped even when one fails
+func (a *Application) stopModules(ctx context.Context) (err error) {
+	for i := len(a.started) - 1; i >= 0; i-- {
+		m := a.started[i]
+		if sErr := m.Stop(ctx); sErr != nil {
+			log.Printf("module %s failed to stop with error `%v`\n", m.Name(), sErr)
+			if err == nil {
+				err = fmt.Errorf("module %s failed to stop: %v", m.Name(), sErr)
+			}
+		}
+	}
+	a.started = nil
+
+	return
+}
+
+// sortModules - orders the modules so every module comes after its dependencies,
+// keeping the registration order otherwise
+func sortModules(modules []Module) ([]Module, error) {
+	index := make(map[string]int, len(modules))
+	for i, m := range modules {
+		if _, ok := index[m.Name()]; ok {
+			return nil, fmt.Errorf("module %s registered twice", m.Name())
+		}
+		index[m.Name()] = i
+	}
+
+	pending := make([]int, len(modules))
+	dependents := make([][]int, len(modules))
+	for i, m := range modules {
+		for _, dep := range m.Dependencies() {
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("module %s depends on unknown module %s", m.Name(), dep)
+			}
+			pending[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	sorted := make([]Module, 0, len(modules))
+	done := make([]bool, len(modules))
+	for len(sorted) < len(modules) {
+		progress := false
+		for i, m := range modules {
+			if done[i] || pending[i] > 0 {
+				continue
+			}
+			done[i], progress = true, true
+			sorted = append(sorted, m)
+			for _, d := range dependents[i] {
+				pending[d]--
+			}
+			// restart so earlier registered modules keep precedence
+			break
+		}
+
+		if !progress {
+			var cycle []string
+			for i, m := range modules {
+				if !done[i] {
+					cycle = append(cycle, m.Name())
+				}
+			}
+			return nil, fmt.Errorf("modules have circular dependencies: %s", strings.Join(cycle, ", "))
+		}
+	}
+
+	return sorted, nil
+}
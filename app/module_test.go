@@ -0,0 +1,111 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"httpframwork/modules/constant"
+)
+
+// stubModule - records its lifecycle calls, fails the hooks listed in fail
+type stubModule struct {
+	name  string
+	deps  []string
+	fail  string
+	calls *[]string
+}
+
+func (me stubModule) Name() string           { return me.name }
+func (me stubModule) Dependencies() []string { return me.deps }
+
+func (me stubModule) Init(*Application) error { return me.record("init") }
+
+func (me stubModule) Start(context.Context) error { return me.record("start") }
+
+func (me stubModule) Stop(context.Context) error { return me.record("stop") }
+
+// record - appends the hook to the calls, it fails when the module is set to fail it
+func (me stubModule) record(hook string) error {
+	*me.calls = append(*me.calls, hook+" "+me.name)
+	if strings.Contains(me.fail, hook) {
+		return errors.New("boom")
+	}
+
+	return nil
+}
+
+func TestModuleLifecycle(t *testing.T) {
+	type spec struct {
+		name string
+		deps []string
+		fail string
+	}
+
+	tests := []struct {
+		name    string
+		modules []spec
+		calls   []string
+		problem string
+	}{
+		{"dependency order", []spec{{"c", []string{"b"}, ""}, {"a", nil, ""}, {"b", []string{"a"}, ""}},
+			[]string{"init a", "init b", "init c", "start a", "start b", "start c", "stop c", "stop b", "stop a"}, ""},
+		{"registration order without dependencies", []spec{{"b", nil, ""}, {"a", nil, ""}},
+			[]string{"init b", "init a", "start b", "start a", "stop a", "stop b"}, ""},
+		{"unknown dependency", []spec{{"a", nil, ""}, {"api", []string{"db"}, ""}},
+			nil, "module api depends on unknown module db"},
+		{"cycle", []spec{{"a", []string{"b"}, ""}, {"b", []string{"a"}, ""}, {"c", nil, ""}},
+			nil, "modules have circular dependencies: a, b"},
+		{"registered twice", []spec{{"a", nil, ""}, {"a", nil, ""}},
+			nil, "module a registered twice"},
+		{"init failure", []spec{{"a", nil, ""}, {"b", nil, "init"}, {"c", nil, ""}},
+			[]string{"init a", "init b"}, "module b failed to init: boom"},
+		// the modules started before the failure are stopped again, in reverse order
+		{"start failure", []spec{{"a", nil, ""}, {"b", nil, ""}, {"c", nil, "start"}, {"d", nil, ""}},
+			[]string{"init a", "init b", "init c", "init d", "start a", "start b", "start c", "stop b", "stop a"},
+			"module c failed to start: boom"},
+		// every module is stopped even when one fails, the first error is reported
+		{"stop failure", []spec{{"a", nil, "stop"}, {"b", nil, "stop"}, {"c", nil, ""}},
+			[]string{"init a", "init b", "init c", "start a", "start b", "start c", "stop c", "stop b", "stop a"},
+			"module b failed to stop: boom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			modules := make([]Module, 0, len(tt.modules))
+			for _, m := range tt.modules {
+				modules = append(modules, stubModule{name: m.name, deps: m.deps, fail: m.fail, calls: &calls})
+			}
+
+			conf := viper.New()
+			conf.Set(constant.ServerShutdownTimeout, time.Second)
+			a := &Application{Config: conf}
+
+			err := a.initModules(modules)
+			if err == nil {
+				err = a.startModules(context.Background())
+			}
+			if err == nil {
+				err = a.stopModules(context.Background())
+			}
+			// nothing is left to stop once the modules were stopped or rolled back
+			if sErr := a.stopModules(context.Background()); sErr != nil {
+				t.Errorf("unexpected error stopping again: %v", sErr)
+			}
+
+			switch {
+			case tt.problem == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.problem != "" && (err == nil || err.Error() != tt.problem):
+				t.Errorf("expected `%s`, got %v", tt.problem, err)
+			}
+			if strings.Join(calls, ", ") != strings.Join(tt.calls, ", ") {
+				t.Errorf("expected calls\n%s\ngot\n%s", strings.Join(tt.calls, ", "), strings.Join(calls, ", "))
+			}
+		})
+	}
+}
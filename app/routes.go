@@ -1,12 +1,23 @@
 package app
 
 import (
+	"io/fs"
 	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"httpframwork/app/api"
 	"httpframwork/app/middleware"
+	"httpframwork/modules/config"
+	"httpframwork/modules/constant"
+	"httpframwork/modules/metrics"
+	"httpframwork/modules/ratelimit"
+	"httpframwork/modules/static"
+	"httpframwork/modules/tracing"
 )
 
 type AppRoutes struct {
@@ -14,11 +25,11 @@ type AppRoutes struct {
 	Path    string
 	Method  []string
 	Handler http.HandlerFunc
+	// Prefix matches every path below Path, prefix routes are tried after the exact ones
+	Prefix bool
 }
 
 var (
-	allowedOrigin = []string{"*"}
-
 	allowedHeaders = []string{"" +
 		"X-Requested-With",
 		"Content-Type",
@@ -53,27 +64,78 @@ func (r AppRoutes) New(name, path string, methods []string, handler http.Handler
 	return rt
 }
 
+// Static - returns a prefix route serving files below path, e.g. from os.DirFS or an embed.FS
+// narrowed with fs.Sub. With spa set, unknown paths without an extension get index.html.
+func (r AppRoutes) Static(name, path string, files fs.FS, spa bool) *AppRoutes {
+	rt := r.New(name, path, []string{http.MethodGet, http.MethodHead}, static.New(files, path, spa).ServeHTTP)
+	rt.Prefix = true
+
+	return rt
+}
+
+// RouteTable - returns the application routes, registering them on first use
+func (a *Application) RouteTable() []*AppRoutes {
+	if a.Routes == nil {
+		// Register the routes here
+		a.Routes = []*AppRoutes{
+			AppRoutes{}.New(api.RegisterHeartbeat(a.Container, a.Config)),
+			AppRoutes{}.New(api.RegisterLivez(a.Container, a.Config)),
+			AppRoutes{}.New(api.RegisterReadyz(a.Container, a.Config)),
+			AppRoutes{}.New(api.RegisterVersion(a.Container, a.Config)),
+			//AppRoutes{}.New(api.RegisterSample(a.Container, a.Config)),
+		}
+
+		// metrics are served on the admin listener, exposing them publicly is an explicit choice
+		if a.Config.GetBool(constant.MetricsEnabled) && a.Config.GetBool(constant.MetricsPublic) {
+			a.Routes = append(a.Routes, AppRoutes{}.New("metrics", a.Config.GetString(constant.MetricsPath),
+				[]string{http.MethodGet}, metrics.GetInstance(a.Container).Handler().ServeHTTP))
+		}
+
+		if a.Config.GetBool(constant.StaticEnabled) {
+			a.Routes = append(a.Routes, AppRoutes{}.Static("static", a.Config.GetString(constant.StaticPath),
+				os.DirFS(a.Config.GetString(constant.StaticDir)), a.Config.GetBool(constant.StaticSPA)))
+		}
+	}
+
+	return a.Routes
+}
+
 // Prepare routes
 func (a *Application) prepareRoutes() http.Handler {
+	a.RouteTable()
 
 	router := mux.NewRouter()
 
-	// Register the routes here
-	a.Routes = []*AppRoutes{
-		AppRoutes{}.New(api.RegisterHeartbeat(a.Container, a.Config)),
-		//AppRoutes{}.New(api.RegisterSample(a.Container, a.Config)),
-	}
-
-	for _, r := range a.Routes {
-		router.
-			Path(r.Path).
+	// exact routes first, then prefix routes from the longest prefix so that / doesn't shadow the others
+	routes := append([]*AppRoutes(nil), a.Routes...)
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Prefix != routes[j].Prefix {
+			return !routes[i].Prefix
+		}
+		return routes[i].Prefix && len(routes[i].Path) > len(routes[j].Path)
+	})
+
+	for _, r := range routes {
+		route := router.NewRoute()
+		if r.Prefix {
+			// the prefix ends on a segment boundary so that /assets doesn't take /assetsfoo, the bare
+			// path redirects to the directory as http.ServeMux does
+			prefix := strings.TrimSuffix(r.Path, "/")
+			route.PathPrefix(prefix + "/")
+			if prefix != "" {
+				router.Path(prefix).Methods(r.Method...).Handler(http.RedirectHandler(prefix+"/", http.StatusMovedPermanently))
+			}
+		} else {
+			route.Path(r.Path)
+		}
+		route.
 			Name(r.Name).
 			HandlerFunc(r.Handler).
 			Methods(r.Method...)
 	}
 
 	a.initMiddleware(router)
-	originsOk := handlers.AllowedOrigins(allowedOrigin)
+	originsOk := handlers.AllowedOriginValidator(a.corsOrigins())
 	headersOk := handlers.AllowedHeaders(allowedHeaders)
 	methodsOk := handlers.AllowedMethods(allowedMethods)
 	//nrgorilla.InstrumentRoutes(a.Server.Router, a.NewRelic)
@@ -87,6 +149,33 @@ func (a *Application) prepareRoutes() http.Handler {
 	return handler
 }
 
+// corsOrigins - returns an origin validator following app.cors.allowed_origins across config reloads
+func (a *Application) corsOrigins() handlers.OriginValidator {
+	var origins atomic.Value
+	origins.Store(a.Config.GetStringSlice(constant.CORSAllowedOrigins))
+
+	config.GetInstance(a.Container).OnStringSlice(constant.CORSAllowedOrigins, func(_, next []string) {
+		origins.Store(next)
+	})
+
+	return func(origin string) bool {
+		for _, o := range origins.Load().([]string) {
+			if o == "*" || o == origin {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 func (a *Application) initMiddleware(router *mux.Router) {
+	if tracer := tracing.GetInstance(a.Container); tracer.Enabled() {
+		router.Use(middleware.Tracing(tracer))
+	}
+	if a.Config.GetBool(constant.MetricsEnabled) {
+		router.Use(middleware.Metrics(metrics.GetInstance(a.Container)))
+	}
+	// installed even without a rate, a config reload can set one
+	router.Use(middleware.RateLimit(ratelimit.GetInstance(a.Container)))
 	router.Use(middleware.Sample)
 }
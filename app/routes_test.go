@@ -0,0 +1,44 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStaticRoute(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{"index.html": "<h1>home</h1>", "app.js": "app"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := newTestApp(t, fmt.Sprintf("  static:\n    enabled: true\n    path: /assets\n    dir: %s\n", dir))
+
+	tests := []struct {
+		path     string
+		code     int
+		location string
+	}{
+		{"/assets/app.js", http.StatusOK, ""},
+		{"/assets/", http.StatusOK, ""},
+		// the bare prefix redirects to the directory, a sibling path is not served
+		{"/assets", http.StatusMovedPermanently, "/assets/"},
+		{"/assetsfoo", http.StatusNotFound, ""},
+		{"/assetsfoo/app.js", http.StatusNotFound, ""},
+		{"/livez", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			a.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.code || rec.Header().Get("Location") != tt.location {
+				t.Errorf("expected %d %q, got %d %q", tt.code, tt.location, rec.Code, rec.Header().Get("Location"))
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package app
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+
+	"httpframwork/app/middleware"
+	"httpframwork/modules/connlimit"
+	"httpframwork/modules/constant"
+)
+
+// endpoint - a listener paired with the server handling its connections
+type endpoint struct {
+	name     string
+	listener net.Listener
+	server   *http.Server
+}
+
+// serve - serves the endpoint, a graceful shutdown is not reported as an error
+func (e *endpoint) serve() (err error) {
+	if e.server.TLSConfig != nil {
+		// certificates are provided by the TLS config
+		err = e.server.ServeTLS(e.listener, "", "")
+	} else {
+		err = e.server.Serve(e.listener)
+	}
+
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return fmt.Errorf("%s listener stopped with error `%v`", e.name, err)
+}
+
+// newServer - builds the http server with the timeouts, limits and protocols from config.
+// A nil tlsConfig builds a plain text server.
+func (a *Application) newServer(handler http.Handler, tlsConfig *tls.Config) *http.Server {
+	srv := &http.Server{
+		Handler:           handler,
+		TLSConfig:         tlsConfig,
+		ReadTimeout:       a.Config.GetDuration(constant.ServerReadTimeout),
+		ReadHeaderTimeout: a.Config.GetDuration(constant.ServerReadHeaderTimeout),
+		WriteTimeout:      a.Config.GetDuration(constant.ServerWriteTimeout),
+		IdleTimeout:       a.Config.GetDuration(constant.ServerIdleTimeout),
+		MaxHeaderBytes:    a.Config.GetInt(constant.ServerMaxHeaderBytes),
+	}
+
+	srv.SetKeepAlivesEnabled(a.Config.GetBool(constant.ServerKeepAlivesEnabled))
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	if a.Config.GetBool(constant.HTTP2Enabled) {
+		if tlsConfig != nil {
+			protocols.SetHTTP2(true)
+		} else {
+			protocols.SetUnencryptedHTTP2(a.Config.GetBool(constant.HTTP2Cleartext))
+		}
+	}
+	srv.Protocols = protocols
+
+	srv.HTTP2 = &http.HTTP2Config{
+		MaxConcurrentStreams: a.Config.GetInt(constant.HTTP2MaxConcurrentStreams),
+		MaxReadFrameSize:     a.Config.GetInt(constant.HTTP2MaxReadFrameSize),
+	}
+
+	return srv
+}
+
+// prepareEndpoints - opens the plain and TLS listeners enabled in config
+func (a *Application) prepareEndpoints(handler http.Handler) (err error) {
+	var ln net.Listener
+
+	sslEnabled := a.Config.GetBool(constant.SSLEnabled)
+	// the public listeners share the connection limits, the admin one stays reachable under load
+	limiter := connlimit.GetInstance(a.Container)
+
+	if a.Config.GetBool(constant.ListenEnabled) {
+		plain := handler
+
+		if a.Config.GetBool(constant.ListenRedirect) {
+			if !sslEnabled {
+				return errors.New("http redirect enabled but ssl disabled")
+			}
+			plain = a.redirectHandler()
+		}
+
+		if ln, err = a.listen(plainListener); err != nil {
+			return
+		}
+		a.addEndpoint("http", limiter.Wrap(ln), plain, nil)
+	}
+
+	if sslEnabled {
+		secure := handler
+
+		if maxAge := a.Config.GetInt(constant.HSTSMaxAge); maxAge > 0 {
+			secure = middleware.HSTS(maxAge, a.Config.GetBool(constant.HSTSSubdomains))(handler)
+		}
+
+		var tlsConfig *tls.Config
+		if tlsConfig, err = a.tlsConfig(); err != nil {
+			return
+		}
+		if ln, err = a.listen(tlsListener); err != nil {
+			return
+		}
+		a.addEndpoint("https", limiter.Wrap(ln), secure, tlsConfig)
+		log.Println("Switched to TLS")
+	}
+
+	if len(a.endpoints) == 0 {
+		return errors.New("no listener enabled")
+	}
+
+	// the admin endpoint is never the only one, diagnostics alone don't serve the application
+	if a.Config.GetBool(constant.AdminEnabled) {
+		if ln, err = a.listen(adminListener); err != nil {
+			return
+		}
+		admin := a.addEndpoint("admin", ln, a.adminHandler(), nil)
+		// profiles and traces stream for as long as they were requested, the public write timeout would cut them off
+		admin.server.WriteTimeout = 0
+	}
+
+	return
+}
+
+// addEndpoint - registers a listener with its own server
+func (a *Application) addEndpoint(name string, ln net.Listener, handler http.Handler, tlsConfig *tls.Config) *endpoint {
+	e := &endpoint{
+		name:     name,
+		listener: ln,
+		server:   a.newServer(handler, tlsConfig),
+	}
+	a.endpoints = append(a.endpoints, e)
+
+	return e
+}
+
+// closeEndpoints - releases listeners that were opened but never served
+func (a *Application) closeEndpoints() {
+	for _, e := range a.endpoints {
+		e.listener.Close()
+	}
+	a.endpoints = nil
+}
+
+// redirectHandler - sends every request to the https origin with a permanent redirect
+func (a *Application) redirectHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, a.httpsOrigin(r)+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// httpsOrigin - returns app.domain when it is https, otherwise the request host on the TLS port
+func (a *Application) httpsOrigin(r *http.Request) string {
+	if pURL, err := url.ParseRequestURI(a.Domain); err == nil && pURL.Scheme == "https" {
+		return "https://" + pURL.Host
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		host = h
+	}
+
+	if port := a.Config.GetString(constant.SSLPort); port != "" && port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+
+	return "https://" + host
+}
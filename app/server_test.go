@@ -0,0 +1,270 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"httpframwork/modules/certificate"
+	"httpframwork/modules/constant"
+)
+
+func TestServerLimits(t *testing.T) {
+	tests := []struct {
+		name                          string
+		yml                           string
+		read, readHeader, write, idle time.Duration
+		maxHeaderBytes                int
+	}{
+		{"defaults", "", 30 * time.Second, 10 * time.Second, time.Minute, 2 * time.Minute, 1 << 20},
+		{"configured", `  server:
+    read_timeout: 5s
+    read_header_timeout: 2s
+    write_timeout: 7s
+    idle_timeout: 9s
+    max_header_bytes: 4096
+`, 5 * time.Second, 2 * time.Second, 7 * time.Second, 9 * time.Second, 4096},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestApp(t, tt.yml).newServer(http.NotFoundHandler(), nil)
+
+			if srv.ReadTimeout != tt.read || srv.ReadHeaderTimeout != tt.readHeader || srv.WriteTimeout != tt.write || srv.IdleTimeout != tt.idle {
+				t.Errorf("unexpected timeouts read %s, header %s, write %s, idle %s",
+					srv.ReadTimeout, srv.ReadHeaderTimeout, srv.WriteTimeout, srv.IdleTimeout)
+			}
+			if srv.MaxHeaderBytes != tt.maxHeaderBytes {
+				t.Errorf("expected %d header bytes, got %d", tt.maxHeaderBytes, srv.MaxHeaderBytes)
+			}
+		})
+	}
+}
+
+func TestServerProtocols(t *testing.T) {
+	type protocols struct{ http1, http2, h2c bool }
+
+	tests := []struct {
+		name               string
+		yml                string
+		plain, secure      protocols
+		streams, frameSize int
+	}{
+		{"defaults", "", protocols{true, false, false}, protocols{true, true, false}, 250, 1 << 20},
+		{"h2c", `  server:
+    http2:
+      h2c: true
+      max_concurrent_streams: 10
+      max_read_frame_size: 32768
+`, protocols{true, false, true}, protocols{true, true, false}, 10, 32768},
+		// h2c rides on http2, disabling it leaves HTTP/1.1 only
+		{"http2 disabled", `  server:
+    http2:
+      enabled: false
+      h2c: true
+`, protocols{true, false, false}, protocols{true, false, false}, 250, 1 << 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t, tt.yml)
+
+			for _, c := range []struct {
+				tlsConfig *tls.Config
+				want      protocols
+			}{{nil, tt.plain}, {&tls.Config{}, tt.secure}} {
+				srv := a.newServer(http.NotFoundHandler(), c.tlsConfig)
+
+				got := protocols{srv.Protocols.HTTP1(), srv.Protocols.HTTP2(), srv.Protocols.UnencryptedHTTP2()}
+				if got != c.want {
+					t.Errorf("tls %v: expected protocols %+v, got %+v", c.tlsConfig != nil, c.want, got)
+				}
+				if srv.HTTP2 == nil || srv.HTTP2.MaxConcurrentStreams != tt.streams || srv.HTTP2.MaxReadFrameSize != tt.frameSize {
+					t.Errorf("unexpected http2 limits %+v", srv.HTTP2)
+				}
+			}
+		})
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprint(enabled), func(t *testing.T) {
+			a := newTestApp(t, fmt.Sprintf("  server:\n    keep_alive: %v\n", enabled))
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := a.newServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), nil)
+			go srv.Serve(ln)
+			defer srv.Close()
+
+			res, err := http.Get("http://" + ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			// without keep-alive the server closes the connection after each response
+			if res.Close == enabled {
+				t.Errorf("keep-alive %v, got Connection: close %v", enabled, res.Close)
+			}
+		})
+	}
+}
+
+func TestAdminServerHasNoWriteTimeout(t *testing.T) {
+	a := serveTestApp(t, fmt.Sprintf(`  listen:
+    host: 127.0.0.1
+    port: %d
+  admin:
+    enabled: true
+    port: %d
+    token: secret
+  server:
+    write_timeout: 200ms
+`, freePort(t), freePort(t)))
+	defer a.Shutdown(context.Background())
+
+	servers := make(map[string]*http.Server)
+	for _, e := range a.endpoints {
+		servers[e.name] = e.server
+	}
+	if got := servers["http"].WriteTimeout; got != 200*time.Millisecond {
+		t.Errorf("expected the public write timeout, got %s", got)
+	}
+	if got := servers["admin"].WriteTimeout; got != 0 {
+		t.Errorf("expected no admin write timeout, got %s", got)
+	}
+
+	// a trace longer than the public write timeout is served in full
+	req, err := http.NewRequest(http.MethodGet, "http://"+a.endpoints[1].listener.Addr().String()+"/debug/pprof/trace?seconds=0.5", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+
+	res, err := (&http.Client{Timeout: 5 * time.Second}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil || res.StatusCode != http.StatusOK || len(body) == 0 {
+		t.Fatalf("trace cut off: status %d, %d bytes, %v", res.StatusCode, len(body), err)
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name, domain, sslPort, target, want string
+	}{
+		{"https domain", "https://app.test", "8443", "http://other.test:8080/a/b?c=d&e=f", "https://app.test/a/b?c=d&e=f"},
+		{"https domain with port", "https://app.test:9443", "443", "http://app.test:8080/", "https://app.test:9443/"},
+		{"default tls port", "http://app.test:8080", "443", "http://app.test:8080/path?q=1", "https://app.test/path?q=1"},
+		{"other tls port", "http://app.test:8080", "8443", "http://app.test:8080/path?q=1", "https://app.test:8443/path?q=1"},
+		{"request host without port", "http://app.test", "8443", "http://www.app.test/", "https://www.app.test:8443/"},
+		{"escaped path", "http://app.test", "443", "http://app.test/a%2Fb?x=%20", "https://app.test/a%2Fb?x=%20"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tlsApp(map[string]interface{}{constant.SSLPort: tt.sslPort})
+			a.Domain = tt.domain
+
+			rec := httptest.NewRecorder()
+			a.redirectHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.target, nil))
+			if rec.Code != http.StatusPermanentRedirect {
+				t.Errorf("expected 308, got %d", rec.Code)
+			}
+			if got := rec.Header().Get("Location"); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHSTSOnTLSListenerOnly(t *testing.T) {
+	dir := t.TempDir()
+	certPEM, keyPEM, err := certificate.GenerateSelfSigned([]string{"app.test"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	os.WriteFile(certFile, certPEM, 0600)
+	os.WriteFile(keyFile, keyPEM, 0600)
+
+	tests := []struct {
+		name     string
+		yml      string
+		plain    int
+		location string
+		hsts     string
+	}{
+		{"hsts", "", http.StatusOK, "", "max-age=31536000"},
+		{"hsts with subdomains and redirect", `    hsts:
+      max_age: 600
+      include_subdomains: true
+`, http.StatusPermanentRedirect, "https://app.test:%d/page?q=1", "max-age=600; includeSubDomains"},
+		{"hsts disabled", `    hsts:
+      max_age: 0
+`, http.StatusOK, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plainPort, tlsPort := freePort(t), freePort(t)
+			redirect := tt.location != ""
+			a := newTestApp(t, fmt.Sprintf(`  listen:
+    host: 127.0.0.1
+    port: %d
+    redirect: %v
+  ssl:
+    enabled: true
+    host: 127.0.0.1
+    port: %d
+    cert: %s
+    key: %s
+`, plainPort, redirect, tlsPort, certFile, keyFile)+tt.yml)
+
+			ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			if err := a.prepareEndpoints(ok); err != nil {
+				t.Fatal(err)
+			}
+			defer a.closeEndpoints()
+
+			for _, e := range a.endpoints {
+				rec := httptest.NewRecorder()
+				e.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://app.test/page?q=1", nil))
+
+				hsts := rec.Header().Get("Strict-Transport-Security")
+				switch e.name {
+				case "http":
+					if hsts != "" {
+						t.Errorf("HSTS sent on the plain listener: %s", hsts)
+					}
+					if rec.Code != tt.plain {
+						t.Errorf("expected %d on the plain listener, got %d", tt.plain, rec.Code)
+					}
+					if want := fmt.Sprintf(tt.location, tlsPort); redirect && rec.Header().Get("Location") != want {
+						t.Errorf("expected a redirect to %s, got %s", want, rec.Header().Get("Location"))
+					}
+				case "https":
+					if hsts != tt.hsts {
+						t.Errorf("expected HSTS `%s` on the TLS listener, got `%s`", tt.hsts, hsts)
+					}
+				}
+			}
+		})
+	}
+}
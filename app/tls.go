@@ -0,0 +1,149 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"httpframwork/modules/certificate"
+	"httpframwork/modules/constant"
+)
+
+// tlsConfig - builds the server TLS configuration from app.ssl
+func (a *Application) tlsConfig() (tlsConfig *tls.Config, err error) {
+	TLSCert := a.Config.GetString(constant.SSLCertFilePath)
+	TLSKey := a.Config.GetString(constant.SSLKeyPath)
+
+	switch {
+	case TLSCert == "" && TLSKey == "":
+		// only developers get a generated certificate, other environments must provide one
+		if a.Config.GetString(constant.AppEnvironment) != constant.EnvironmentLocal {
+			return nil, errors.New("ssl enabled but certificate missing")
+		}
+		if a.certificates, err = a.selfSignedCertificate(); err != nil {
+			return
+		}
+	case TLSCert == "" || TLSKey == "":
+		// a configured file is never silently replaced by a generated pair
+		return nil, fmt.Errorf("%s and %s must be set together", constant.SSLCertFilePath, constant.SSLKeyPath)
+	default:
+		if a.certificates, err = certificate.NewLoader(TLSCert, TLSKey); err != nil {
+			return
+		}
+	}
+
+	if interval := a.Config.GetDuration(constant.SSLReloadInterval); interval > 0 {
+		a.certificates.Watch(interval)
+	}
+
+	tlsConfig = &tls.Config{GetCertificate: a.certificates.GetCertificate}
+
+	if err = a.clientAuth(tlsConfig); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// clientAuth - applies the client certificate verification mode and CA bundle from app.ssl
+func (a *Application) clientAuth(tlsConfig *tls.Config) (err error) {
+	mode := a.Config.GetString(constant.SSLClientAuth)
+
+	switch mode {
+	case "", "none":
+		tlsConfig.ClientAuth = tls.NoClientCert
+		return
+	case "request":
+		tlsConfig.ClientAuth = tls.RequestClientCert
+	case "require":
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	case "verify_if_given":
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return fmt.Errorf("unsupported client auth mode `%s`", mode)
+	}
+
+	caFile := a.Config.GetString(constant.SSLClientCA)
+	if caFile == "" {
+		if tlsConfig.ClientAuth == tls.RequestClientCert {
+			return
+		}
+		return fmt.Errorf("client auth `%s` requires a client CA bundle", mode)
+	}
+
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found in client CA bundle `%s`", caFile)
+	}
+	tlsConfig.ClientCAs = pool
+
+	log.Printf("Client certificate auth `%s` enabled\n", mode)
+
+	return
+}
+
+// selfSignedCertificate - generates a development certificate for the app.domain host.
+// When persisting is enabled the pair is written to disk and reused on the next start.
+func (a *Application) selfSignedCertificate() (loader *certificate.Loader, err error) {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if pURL, pErr := url.ParseRequestURI(a.Domain); pErr == nil && pURL.Hostname() != "localhost" {
+		hosts = append([]string{pURL.Hostname()}, hosts...)
+	}
+
+	persist := a.Config.GetBool(constant.SSLSelfSignedPersist)
+	dir := a.Config.GetString(constant.SSLSelfSignedDir)
+	certFile := filepath.Join(dir, "selfsigned.crt")
+	keyFile := filepath.Join(dir, "selfsigned.key")
+
+	if persist {
+		if loader, err = certificate.NewLoader(certFile, keyFile); err == nil {
+			return
+		}
+	}
+
+	log.Printf("Generating self-signed certificate for %s\n", strings.Join(hosts, ", "))
+
+	certPEM, keyPEM, err := certificate.GenerateSelfSigned(hosts, constant.DefaultSelfSignedTTL)
+	if err != nil {
+		return
+	}
+
+	if !persist {
+		return certificate.NewStaticLoader(certPEM, keyPEM)
+	}
+
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return
+	}
+	if err = ioutil.WriteFile(certFile, certPEM, 0644); err != nil {
+		return
+	}
+	if err = ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return
+	}
+
+	return certificate.NewLoader(certFile, keyFile)
+}
+
+// reloadCertificates - re-reads the certificate pair, the current one is kept when the new one is broken
+func (a *Application) reloadCertificates() {
+	if a.certificates == nil {
+		return
+	}
+
+	if err := a.certificates.Reload(); err != nil {
+		log.Println(err.Error() + ", keeping the previous certificate")
+	}
+}
@@ -0,0 +1,283 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"httpframwork/app/api"
+	"httpframwork/modules/certificate"
+	"httpframwork/modules/constant"
+)
+
+// tlsApp - returns an application with the defaults and the given ssl settings
+func tlsApp(settings map[string]interface{}) *Application {
+	conf := viper.New()
+	setDefaults(conf)
+	conf.Set(constant.AppEnvironment, constant.EnvironmentLocal)
+	conf.Set(constant.SSLReloadInterval, 0)
+	for k, v := range settings {
+		conf.Set(k, v)
+	}
+
+	return &Application{Config: conf, Domain: "https://app.test:8443"}
+}
+
+func TestTLSConfigCertificateSource(t *testing.T) {
+	dir := t.TempDir()
+	certPEM, keyPEM, err := certificate.GenerateSelfSigned([]string{"configured.test"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	os.WriteFile(certFile, certPEM, 0600)
+	os.WriteFile(keyFile, keyPEM, 0600)
+
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		subject  string
+		problem  string
+	}{
+		{"configured pair", map[string]interface{}{constant.SSLCertFilePath: certFile, constant.SSLKeyPath: keyFile},
+			"configured.test", ""},
+		{"generated pair", nil, "app.test", ""},
+		{"certificate without key", map[string]interface{}{constant.SSLCertFilePath: certFile},
+			"", "must be set together"},
+		{"key without certificate", map[string]interface{}{constant.SSLKeyPath: keyFile},
+			"", "must be set together"},
+		{"generated outside local", map[string]interface{}{constant.AppEnvironment: "production"},
+			"", "certificate missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tlsApp(tt.settings)
+			conf, err := a.tlsConfig()
+			if tt.problem != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.problem) {
+					t.Fatalf("expected `%s`, got %v", tt.problem, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cert, _ := conf.GetCertificate(nil)
+			if cn := cert.Leaf.Subject.CommonName; cn != tt.subject {
+				t.Errorf("expected a certificate for %s, got %s", tt.subject, cn)
+			}
+		})
+	}
+}
+
+// testCA - a certificate authority issuing client certificates
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCA - generates a CA, its certificate is written to ca.pem in dir
+func newTestCA(t *testing.T, dir string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	ca := &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+	if err = os.WriteFile(filepath.Join(dir, "ca.pem"), ca.pem, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return ca
+}
+
+// issue - returns a client certificate for the common name, signed by the CA
+func (ca *testCA) issue(t *testing.T, commonName string) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, _ := url.Parse("spiffe://test/client")
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(time.Now().UnixNano()),
+		Subject:        pkix.Name{CommonName: commonName, Organization: []string{"test"}},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		DNSNames:       []string{commonName},
+		EmailAddresses: []string{"ops@client.test"},
+		IPAddresses:    []net.IP{net.IPv4(10, 0, 0, 1)},
+		URIs:           []*url.URL{uri},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestClientAuthModes(t *testing.T) {
+	dir := t.TempDir()
+	newTestCA(t, dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	notPEM := filepath.Join(dir, "not.pem")
+	os.WriteFile(notPEM, []byte("not a certificate"), 0600)
+
+	tests := []struct {
+		mode, ca string
+		want     tls.ClientAuthType
+		pool     bool
+		problem  string
+	}{
+		{"", "", tls.NoClientCert, false, ""},
+		{"none", caFile, tls.NoClientCert, false, ""},
+		{"request", "", tls.RequestClientCert, false, ""},
+		{"request", caFile, tls.RequestClientCert, true, ""},
+		{"require", caFile, tls.RequireAndVerifyClientCert, true, ""},
+		{"verify_if_given", caFile, tls.VerifyClientCertIfGiven, true, ""},
+		{"require", "", 0, false, "client auth `require` requires a client CA bundle"},
+		{"verify_if_given", "", 0, false, "client auth `verify_if_given` requires a client CA bundle"},
+		{"require", filepath.Join(dir, "missing.pem"), 0, false, "no such file"},
+		{"verify_if_given", dir, 0, false, "is a directory"},
+		{"require", notPEM, 0, false, "no certificates found in client CA bundle"},
+		{"optional", caFile, 0, false, "unsupported client auth mode `optional`"},
+	}
+
+	for _, tt := range tests {
+		ca := "no CA"
+		if tt.ca != "" {
+			ca = filepath.Base(tt.ca)
+		}
+		t.Run(tt.mode+" with "+ca, func(t *testing.T) {
+			a := tlsApp(map[string]interface{}{constant.SSLClientAuth: tt.mode, constant.SSLClientCA: tt.ca})
+
+			conf := &tls.Config{}
+			err := a.clientAuth(conf)
+			if tt.problem != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.problem) {
+					t.Fatalf("expected `%s`, got %v", tt.problem, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if conf.ClientAuth != tt.want || (conf.ClientCAs != nil) != tt.pool {
+				t.Errorf("expected %v with a CA pool %v, got %v %v", tt.want, tt.pool, conf.ClientAuth, conf.ClientCAs != nil)
+			}
+		})
+	}
+}
+
+func TestPeerIdentity(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	trusted := ca.issue(t, "client.test")
+	untrusted := newTestCA(t, t.TempDir()).issue(t, "intruder.test")
+
+	tests := []struct {
+		mode    string
+		cert    *tls.Certificate
+		peer    string
+		refused bool
+	}{
+		{"none", &trusted, "", false},
+		// requested certificates are not verified, they never make a peer
+		{"request", &trusted, "", false},
+		{"request", &untrusted, "", false},
+		{"verify_if_given", nil, "", false},
+		{"verify_if_given", &trusted, "CN=client.test,O=test", false},
+		{"verify_if_given", &untrusted, "", true},
+		{"require", nil, "", true},
+		{"require", &trusted, "CN=client.test,O=test", false},
+	}
+
+	for _, tt := range tests {
+		name := tt.mode + " without certificate"
+		if tt.cert != nil {
+			name = tt.mode + " " + tt.cert.Leaf.Subject.CommonName
+		}
+		t.Run(name, func(t *testing.T) {
+			peers := make(chan *api.Peer, 1)
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				peers <- api.PeerIdentity(r)
+			}))
+			srv.TLS = &tls.Config{}
+			a := tlsApp(map[string]interface{}{constant.SSLClientAuth: tt.mode, constant.SSLClientCA: filepath.Join(dir, "ca.pem")})
+			if err := a.clientAuth(srv.TLS); err != nil {
+				t.Fatal(err)
+			}
+			srv.StartTLS()
+			defer srv.Close()
+
+			client := srv.Client()
+			if tt.cert != nil {
+				client.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{*tt.cert}
+			}
+			res, err := client.Get(srv.URL)
+			if tt.refused {
+				if err == nil {
+					res.Body.Close()
+					t.Fatal("expected the handshake to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			peer := <-peers
+			switch {
+			case tt.peer == "" && peer != nil:
+				t.Errorf("expected no peer, got %s", peer.Subject)
+			case tt.peer != "" && peer == nil:
+				t.Errorf("expected the peer %s", tt.peer)
+			case tt.peer != "":
+				want := []string{"client.test", "ops@client.test", "10.0.0.1", "spiffe://test/client"}
+				if peer.Subject != tt.peer || peer.CommonName != "client.test" || strings.Join(peer.SANs(), " ") != strings.Join(want, " ") {
+					t.Errorf("unexpected peer %s %s %v", peer.Subject, peer.CommonName, peer.SANs())
+				}
+			}
+		})
+	}
+}
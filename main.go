@@ -1,21 +1,12 @@
 package main
 
 import (
-	"log"
+	"os"
 
-	"httpframwork/app"
+	"httpframwork/app/cli"
 )
 
-// main application entry point
+// main application entry point, see `help` for the available commands
 func main() {
-	var application app.Application
-	var err error
-	application, err = app.New()
-	if err != nil {
-		log.Fatal("failed to start the server: " + err.Error())
-	}
-
-	if err := application.Run(); err != nil {
-		panic(err)
-	}
+	os.Exit(cli.Run(os.Args[1:]))
 }
@@ -0,0 +1,157 @@
+package certificate
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Loader serves a certificate pair from disk and swaps it when the files change.
+// A broken pair is never swapped in, the previous certificate keeps being served.
+type Loader struct {
+	sync.RWMutex
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	modTime  time.Time
+	stop     chan struct{}
+}
+
+// NewLoader creates new loader and reads the initial certificate pair
+func NewLoader(certFile, keyFile string) (*Loader, error) {
+	l := &Loader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// GetCertificate returns the current certificate, to be used as tls.Config.GetCertificate
+func (me *Loader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	me.RLock()
+	defer me.RUnlock()
+
+	return me.cert, nil
+}
+
+// Reload reads and validates the pair from disk, then swaps it with the current one
+func (me *Loader) Reload() (err error) {
+	if me.certFile == "" {
+		// in-memory certificate, nothing to reload
+		return
+	}
+
+	modTime := me.latestModTime()
+
+	cert, err := load(me.certFile, me.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate `%s` with error `%v`", me.certFile, err)
+	}
+
+	me.Lock()
+	me.cert = cert
+	me.modTime = modTime
+	me.Unlock()
+
+	log.Printf("Loaded certificate %s for %s, expires %s\n",
+		me.certFile, cert.Leaf.Subject.CommonName, cert.Leaf.NotAfter.UTC().Format(time.RFC3339))
+
+	return
+}
+
+// Watch polls the files every interval and reloads the pair when they change
+func (me *Loader) Watch(interval time.Duration) {
+	me.Lock()
+	if me.stop != nil || me.certFile == "" {
+		me.Unlock()
+		return
+	}
+	me.stop = make(chan struct{})
+	stop := me.stop
+	me.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if !me.changed() {
+					continue
+				}
+				if err := me.Reload(); err != nil {
+					log.Println(err.Error() + ", keeping the previous certificate")
+					// remember the broken files so they are not retried on every tick
+					me.Lock()
+					me.modTime = me.latestModTime()
+					me.Unlock()
+				}
+			}
+		}
+	}()
+}
+
+// Stop ends the file watch
+func (me *Loader) Stop() {
+	me.Lock()
+	defer me.Unlock()
+
+	if me.stop != nil {
+		close(me.stop)
+		me.stop = nil
+	}
+}
+
+// changed reports whether the files were modified since the last load
+func (me *Loader) changed() bool {
+	modTime := me.latestModTime()
+
+	me.RLock()
+	defer me.RUnlock()
+
+	return modTime.After(me.modTime)
+}
+
+// latestModTime returns the most recent modification time of the pair
+func (me *Loader) latestModTime() (t time.Time) {
+	for _, f := range []string{me.certFile, me.keyFile} {
+		if info, err := os.Stat(f); err == nil && info.ModTime().After(t) {
+			t = info.ModTime()
+		}
+	}
+
+	return
+}
+
+// load reads the pair and makes sure it is usable
+func load(certFile, keyFile string) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	if time.Now().After(cert.Leaf.NotAfter) {
+		return nil, errors.New("certificate expired on " + cert.Leaf.NotAfter.UTC().Format(time.RFC3339))
+	}
+
+	return &cert, nil
+}
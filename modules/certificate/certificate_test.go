@@ -0,0 +1,197 @@
+package certificate
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writePair - writes a self-signed pair for host into dir, with a modification time of at
+func writePair(t *testing.T, dir, host string, validFor time.Duration, at time.Time) (certFile, keyFile string) {
+	t.Helper()
+
+	certPEM, keyPEM, err := GenerateSelfSigned([]string{host}, validFor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	write(t, certFile, certPEM, at)
+	write(t, keyFile, keyPEM, at)
+
+	return
+}
+
+func write(t *testing.T, file string, data []byte, at time.Time) {
+	t.Helper()
+
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, at, at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// commonName - returns the subject of the certificate the loader currently serves
+func commonName(t *testing.T, l *Loader) string {
+	t.Helper()
+
+	cert, err := l.GetCertificate(nil)
+	if err != nil || cert == nil {
+		t.Fatalf("no certificate served: %v", err)
+	}
+
+	return cert.Leaf.Subject.CommonName
+}
+
+func TestNewLoader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writePair(t, dir, "first.test", time.Hour, time.Now())
+
+	l, err := NewLoader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cn := commonName(t, l); cn != "first.test" {
+		t.Errorf("expected first.test, got %s", cn)
+	}
+
+	if _, err = NewLoader(filepath.Join(dir, "missing.crt"), keyFile); err == nil {
+		t.Error("expected an error for a missing certificate")
+	}
+
+	expired, expiredKey := writePair(t, t.TempDir(), "expired.test", time.Second, time.Now())
+	if _, err = NewLoader(expired, expiredKey); err == nil {
+		t.Error("expected an error for an expired certificate")
+	}
+}
+
+func TestReloadKeepsPreviousOnInvalidPair(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writePair(t, dir, "first.test", time.Hour, time.Now())
+
+	l, err := NewLoader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, broken := range map[string]func(){
+		"garbage certificate": func() { write(t, certFile, []byte("not a certificate"), time.Now()) },
+		"mismatched key": func() {
+			_, otherKey := writePair(t, t.TempDir(), "other.test", time.Hour, time.Now())
+			key, _ := os.ReadFile(otherKey)
+			writePair(t, dir, "second.test", time.Hour, time.Now())
+			write(t, keyFile, key, time.Now())
+		},
+		"expired certificate": func() { writePair(t, dir, "expired.test", time.Second, time.Now()) },
+		"missing key":         func() { os.Remove(keyFile) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			broken()
+			if err := l.Reload(); err == nil {
+				t.Fatal("expected the reload to fail")
+			}
+			if cn := commonName(t, l); cn != "first.test" {
+				t.Errorf("expected the previous certificate, got %s", cn)
+			}
+		})
+	}
+
+	writePair(t, dir, "second.test", time.Hour, time.Now())
+	if err = l.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if cn := commonName(t, l); cn != "second.test" {
+		t.Errorf("expected second.test, got %s", cn)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	certFile, keyFile := writePair(t, dir, "first.test", time.Hour, start)
+
+	l, err := NewLoader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Watch(5 * time.Millisecond)
+	defer l.Stop()
+
+	// handshakes keep reading the certificate while it is swapped
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				l.GetCertificate(nil)
+			}
+		}
+	}()
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	eventually := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if commonName(t, l) == want {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("expected %s, got %s", want, commonName(t, l))
+	}
+
+	writePair(t, dir, "second.test", time.Hour, start.Add(time.Minute))
+	eventually("second.test")
+
+	// a broken pair is skipped and remembered, the next valid one is picked up
+	write(t, certFile, []byte("not a certificate"), start.Add(2*time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if cn := commonName(t, l); cn != "second.test" {
+		t.Fatalf("expected the previous certificate, got %s", cn)
+	}
+
+	writePair(t, dir, "third.test", time.Hour, start.Add(3*time.Minute))
+	eventually("third.test")
+}
+
+func TestStaticLoader(t *testing.T) {
+	certPEM, keyPEM, err := GenerateSelfSigned([]string{"localhost", "127.0.0.1"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := NewStaticLoader(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, _ := l.GetCertificate(nil)
+	leaf := cert.Leaf
+	if leaf.Subject.CommonName != "localhost" || len(leaf.IPAddresses) != 1 || len(leaf.DNSNames) != 1 {
+		t.Errorf("unexpected names %s %v %v", leaf.Subject.CommonName, leaf.DNSNames, leaf.IPAddresses)
+	}
+
+	// nothing to reload or watch for an in-memory pair
+	if err = l.Reload(); err != nil {
+		t.Error(err)
+	}
+	l.Watch(time.Millisecond)
+	l.Stop()
+
+	if _, err = NewStaticLoader(certPEM, []byte("not a key")); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+}
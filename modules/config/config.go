@@ -0,0 +1,254 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"httpframwork/modules/container"
+)
+
+type (
+	// Change describes a key whose value differs between the previous and the new config
+	Change struct {
+		Key string
+		Old interface{}
+		New interface{}
+	}
+
+	// Subscriber is notified after a new config was swapped in
+	Subscriber func(prev, next *viper.Viper, changes []Change)
+
+	// Validator rejects a new config before it is swapped in
+	Validator func(conf *viper.Viper) error
+
+	subscription struct {
+		prefix string
+		fn     Subscriber
+	}
+
+	// Store holds the current application config and notifies subscribers when it is replaced
+	Store struct {
+		sync.RWMutex
+		// swap serializes Swap, a config is compared with the one it replaces
+		swap        sync.Mutex
+		current     *viper.Viper
+		sources     Sources
+		immutable   []string
+		validators  []Validator
+		subscribers []subscription
+	}
+)
+
+const (
+	InstanceKey = "Config"
+)
+
+// GetRegistry function ...
+func GetRegistry() container.Registries {
+	obj := &Store{}
+
+	return container.Registries{
+		container.Registry{
+			Key:   InstanceKey,
+			Value: obj,
+		},
+	}
+}
+
+// GetInstance function ...
+func GetInstance(c *container.Container) *Store {
+	return c.Get(InstanceKey).(*Store)
+}
+
+// Init method - sets the config loaded at startup with the sources of its values
+func (me *Store) Init(conf *viper.Viper, sources Sources) {
+	me.Lock()
+	me.current = conf
+	me.sources = sources
+	me.Unlock()
+}
+
+// Current method - returns the config in effect, it must not be modified
+func (me *Store) Current() *viper.Viper {
+	me.RLock()
+	defer me.RUnlock()
+
+	return me.current
+}
+
+// Sources method - returns where every value of the current config came from
+func (me *Store) Sources() Sources {
+	me.RLock()
+	defer me.RUnlock()
+
+	return me.sources
+}
+
+// Redacted method - returns the current settings with the secret values replaced
+func (me *Store) Redacted() map[string]interface{} {
+	me.RLock()
+	defer me.RUnlock()
+
+	return Redact(me.current, me.sources)
+}
+
+// Immutable method - marks keys (or key prefixes) that can only change with a restart
+func (me *Store) Immutable(keys ...string) *Store {
+	me.Lock()
+	me.immutable = append(me.immutable, keys...)
+	me.Unlock()
+
+	return me
+}
+
+// Validate method - registers a check every new config must pass
+func (me *Store) Validate(fn Validator) *Store {
+	me.Lock()
+	me.validators = append(me.validators, fn)
+	me.Unlock()
+
+	return me
+}
+
+// Subscribe method - calls fn when a key under prefix changes, an empty prefix matches every key
+func (me *Store) Subscribe(prefix string, fn Subscriber) *Store {
+	me.Lock()
+	me.subscribers = append(me.subscribers, subscription{prefix: prefix, fn: fn})
+	me.Unlock()
+
+	return me
+}
+
+// OnString method - calls fn with the old and new value when key changes
+func (me *Store) OnString(key string, fn func(prev, next string)) *Store {
+	return me.Subscribe(key, func(prev, next *viper.Viper, _ []Change) {
+		fn(prev.GetString(key), next.GetString(key))
+	})
+}
+
+// OnStringSlice method - calls fn with the old and new value when key changes
+func (me *Store) OnStringSlice(key string, fn func(prev, next []string)) *Store {
+	return me.Subscribe(key, func(prev, next *viper.Viper, _ []Change) {
+		fn(prev.GetStringSlice(key), next.GetStringSlice(key))
+	})
+}
+
+// OnBool method - calls fn with the old and new value when key changes
+func (me *Store) OnBool(key string, fn func(prev, next bool)) *Store {
+	return me.Subscribe(key, func(prev, next *viper.Viper, _ []Change) {
+		fn(prev.GetBool(key), next.GetBool(key))
+	})
+}
+
+// OnInt method - calls fn with the old and new value when key changes
+func (me *Store) OnInt(key string, fn func(prev, next int)) *Store {
+	return me.Subscribe(key, func(prev, next *viper.Viper, _ []Change) {
+		fn(prev.GetInt(key), next.GetInt(key))
+	})
+}
+
+// OnDuration method - calls fn with the old and new value when key changes
+func (me *Store) OnDuration(key string, fn func(prev, next time.Duration)) *Store {
+	return me.Subscribe(key, func(prev, next *viper.Viper, _ []Change) {
+		fn(prev.GetDuration(key), next.GetDuration(key))
+	})
+}
+
+// Swap method - validates the new config and replaces the current one.
+// Changes to immutable keys are rejected and keep their current value.
+// Concurrent swaps run one after the other, subscribers are notified in the same order.
+func (me *Store) Swap(next *viper.Viper, sources Sources) (changes []Change, err error) {
+	me.swap.Lock()
+	defer me.swap.Unlock()
+
+	me.RLock()
+	prev := me.current
+	prevSources := me.sources
+	validators := me.validators
+	immutable := me.immutable
+	me.RUnlock()
+
+	for _, c := range Diff(prev, next) {
+		if matchAny(c.Key, immutable) {
+			log.Printf("Config key %s can not change at runtime (%v -> %v), restart required\n",
+				c.Key, prevSources.Display(c.Key, c.Old), sources.Display(c.Key, c.New))
+			next.Set(c.Key, c.Old)
+			sources[c.Key] = prevSources[c.Key]
+			continue
+		}
+		changes = append(changes, c)
+	}
+
+	if len(changes) == 0 {
+		return
+	}
+
+	for _, fn := range validators {
+		if err = fn(next); err != nil {
+			return nil, fmt.Errorf("new config rejected: %v", err)
+		}
+	}
+
+	me.Lock()
+	me.current = next
+	me.sources = sources
+	subscribers := me.subscribers
+	me.Unlock()
+
+	for _, s := range subscribers {
+		var matched []Change
+		for _, c := range changes {
+			if match(c.Key, s.prefix) {
+				matched = append(matched, c)
+			}
+		}
+		if len(matched) > 0 {
+			s.fn(prev, next, matched)
+		}
+	}
+
+	return
+}
+
+// Diff function - returns the keys whose values differ between the two configs, sorted by key
+func Diff(prev, next *viper.Viper) (changes []Change) {
+	keys := make(map[string]bool)
+	for _, k := range prev.AllKeys() {
+		keys[k] = true
+	}
+	for _, k := range next.AllKeys() {
+		keys[k] = true
+	}
+
+	for k := range keys {
+		if o, n := prev.Get(k), next.Get(k); !reflect.DeepEqual(o, n) {
+			changes = append(changes, Change{Key: k, Old: o, New: n})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+
+	return
+}
+
+// match reports whether key equals prefix or is nested under it
+func match(key, prefix string) bool {
+	return prefix == "" || key == prefix || strings.HasPrefix(key, prefix+".")
+}
+
+// matchAny reports whether key matches one of the prefixes
+func matchAny(key string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if match(key, p) {
+			return true
+		}
+	}
+
+	return false
+}
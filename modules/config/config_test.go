@@ -0,0 +1,179 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// settings - returns a config holding the given dotted keys
+func settings(values map[string]interface{}) *viper.Viper {
+	conf := viper.New()
+	for k, v := range values {
+		conf.Set(k, v)
+	}
+
+	return conf
+}
+
+func TestDiff(t *testing.T) {
+	prev := settings(map[string]interface{}{
+		"app.log_level": "info",
+		"app.port":      8080,
+		"app.removed":   true,
+		"app.origins":   []string{"a"},
+	})
+	next := settings(map[string]interface{}{
+		"app.log_level": "debug",
+		"app.port":      8080,
+		"app.added":     "x",
+		"app.origins":   []string{"a", "b"},
+	})
+
+	var keys []string
+	for _, c := range Diff(prev, next) {
+		keys = append(keys, c.Key)
+	}
+
+	want := []string{"app.added", "app.log_level", "app.origins", "app.removed"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("expected %v, got %v", want, keys)
+	}
+
+	if changes := Diff(prev, prev); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
+
+func TestSwap(t *testing.T) {
+	store := &Store{}
+	store.Init(settings(map[string]interface{}{
+		"app.log_level":      "info",
+		"app.listen.port":    8080,
+		"app.health.timeout": "2s",
+	}), Sources{})
+	store.Immutable("app.listen")
+
+	var (
+		all     []string
+		level   [2]string
+		timeout [2]time.Duration
+		port    bool
+	)
+	store.
+		Subscribe("", func(_, _ *viper.Viper, changes []Change) {
+			for _, c := range changes {
+				all = append(all, c.Key)
+			}
+		}).
+		OnString("app.log_level", func(prev, next string) { level = [2]string{prev, next} }).
+		OnDuration("app.health.timeout", func(prev, next time.Duration) { timeout = [2]time.Duration{prev, next} }).
+		Subscribe("app.listen", func(*viper.Viper, *viper.Viper, []Change) { port = true })
+
+	next := settings(map[string]interface{}{
+		"app.log_level":      "debug",
+		"app.listen.port":    9090,
+		"app.health.timeout": "2s",
+	})
+	changes, err := store.Swap(next, Sources{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changes) != 1 || changes[0].Key != "app.log_level" {
+		t.Errorf("expected only app.log_level to change, got %v", changes)
+	}
+	if !reflect.DeepEqual(all, []string{"app.log_level"}) {
+		t.Errorf("expected the catch-all subscriber to get app.log_level, got %v", all)
+	}
+	if level != [2]string{"info", "debug"} {
+		t.Errorf("unexpected log level change %v", level)
+	}
+	if timeout != [2]time.Duration{} {
+		t.Errorf("unchanged key notified: %v", timeout)
+	}
+	if port {
+		t.Error("immutable key notified")
+	}
+	if got := store.Current().GetInt("app.listen.port"); got != 8080 {
+		t.Errorf("expected the immutable port to keep 8080, got %d", got)
+	}
+	if got := store.Current().GetString("app.log_level"); got != "debug" {
+		t.Errorf("expected the new log level, got %s", got)
+	}
+}
+
+func TestSwapRejected(t *testing.T) {
+	initial := settings(map[string]interface{}{"app.log_level": "info"})
+	store := &Store{}
+	store.Init(initial, Sources{})
+
+	notified := false
+	store.
+		Validate(func(conf *viper.Viper) error {
+			if conf.GetString("app.log_level") == "loud" {
+				return errors.New("unknown level")
+			}
+			return nil
+		}).
+		Subscribe("", func(*viper.Viper, *viper.Viper, []Change) { notified = true })
+
+	_, err := store.Swap(settings(map[string]interface{}{"app.log_level": "loud"}), Sources{})
+	if err == nil {
+		t.Fatal("expected the new config to be rejected")
+	}
+	if notified || store.Current() != initial {
+		t.Error("a rejected config was swapped in")
+	}
+
+	// nothing changed, nothing to validate or notify
+	if changes, err := store.Swap(settings(map[string]interface{}{"app.log_level": "info"}), Sources{}); err != nil || changes != nil {
+		t.Errorf("expected no changes, got %v %v", changes, err)
+	}
+}
+
+func TestSwapConcurrently(t *testing.T) {
+	store := &Store{}
+	store.Init(settings(map[string]interface{}{"app.version": 0}), Sources{})
+
+	// every notification continues from the config the previous one swapped in
+	var (
+		mu   sync.Mutex
+		last = 0
+		gaps []string
+	)
+	store.Subscribe("app.version", func(prev, next *viper.Viper, _ []Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		if prev.GetInt("app.version") != last {
+			gaps = append(gaps, fmt.Sprintf("%d after %d", prev.GetInt("app.version"), last))
+		}
+		last = next.GetInt("app.version")
+	})
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			store.Swap(settings(map[string]interface{}{"app.version": i}), Sources{})
+		}(i)
+		go func() {
+			defer wg.Done()
+			store.Current().GetInt("app.version")
+		}()
+	}
+	wg.Wait()
+
+	if len(gaps) > 0 {
+		t.Errorf("subscribers saw interleaved swaps: %v", gaps)
+	}
+	if got := store.Current().GetInt("app.version"); got != last {
+		t.Errorf("current config %d differs from the last notified %d", got, last)
+	}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// SourceDefault marks values that come from the registered defaults
+	SourceDefault = "default"
+	// OverrideName is the optional file merged after the environment file
+	OverrideName = "override"
+	// SourceMemory marks values given in code rather than read from a file
+	SourceMemory = "memory"
+	// EnvironmentKey selects the environment file to merge
+	EnvironmentKey = "app.environment"
+)
+
+// Sources maps every config key to the layer its final value came from
+type Sources map[string]string
+
+// Layers returns the files merged for the environment, in merge order
+func Layers(dir, name, environment string) []string {
+	files := []string{filepath.Join(dir, name+".yml")}
+	if environment != "" {
+		files = append(files, filepath.Join(dir, name+"."+environment+".yml"))
+	}
+
+	return append(files, filepath.Join(dir, name+"."+OverrideName+".yml"))
+}
+
+// EnvName returns the environment variable overriding the key, app.ssl.enabled is APP_SSL_ENABLED
+func EnvName(key string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// Load merges <name>.yml, <name>.<environment>.yml, <name>.override.yml from dir and
+// the environment variables into conf, in that order, then resolves the secret references.
+// Only the base file is required.
+func Load(conf *viper.Viper, dir, name string) (sources Sources, files []string, err error) {
+	sources = make(Sources)
+	for _, k := range conf.AllKeys() {
+		sources[k] = SourceDefault
+	}
+
+	conf.SetConfigType("yaml")
+	conf.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	conf.AutomaticEnv()
+
+	base := filepath.Join(dir, name+".yml")
+	if err = mergeFile(conf, base, sources, true); err != nil {
+		return
+	}
+
+	files = Layers(dir, name, conf.GetString(EnvironmentKey))
+	for _, f := range files[1:] {
+		if err = mergeFile(conf, f, sources, false); err != nil {
+			return
+		}
+	}
+
+	for _, k := range conf.AllKeys() {
+		if _, ok := os.LookupEnv(EnvName(k)); ok {
+			sources[k] = "env:" + EnvName(k)
+		}
+	}
+
+	err = ResolveSecrets(conf, sources)
+
+	return
+}
+
+// LoadMap merges the nested settings into conf and resolves the secret references,
+// neither files nor environment variables are read
+func LoadMap(conf *viper.Viper, settings map[string]interface{}) (sources Sources, err error) {
+	sources = make(Sources)
+	for _, k := range conf.AllKeys() {
+		sources[k] = SourceDefault
+	}
+
+	layer := viper.New()
+	if err = layer.MergeConfigMap(settings); err != nil {
+		return
+	}
+	if err = conf.MergeConfigMap(layer.AllSettings()); err != nil {
+		return nil, fmt.Errorf("failed to merge config with error `%v`", err)
+	}
+
+	for _, k := range layer.AllKeys() {
+		sources[k] = SourceMemory
+	}
+
+	err = ResolveSecrets(conf, sources)
+
+	return
+}
+
+// mergeFile merges one yaml file into conf and records the keys it sets
+func mergeFile(conf *viper.Viper, file string, sources Sources, required bool) error {
+	if _, err := os.Stat(file); os.IsNotExist(err) && !required {
+		return nil
+	}
+
+	layer := viper.New()
+	layer.SetConfigFile(file)
+	layer.SetConfigType("yaml")
+	if err := layer.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config `%s` with error `%v`", file, err)
+	}
+
+	if err := conf.MergeConfigMap(layer.AllSettings()); err != nil {
+		return fmt.Errorf("failed to merge config `%s` with error `%v`", file, err)
+	}
+
+	for _, k := range layer.AllKeys() {
+		sources[k] = file
+	}
+
+	return nil
+}
+
+// Print writes every key with its final value and the layer it came from, sorted by key.
+// Secret values are replaced when redact is set.
+func (me Sources) Print(w io.Writer, conf *viper.Viper, redact bool) {
+	keys := make([]string, 0, len(me))
+	for k := range me {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		value := conf.Get(k)
+		if redact {
+			value = me.Display(k, value)
+		}
+		fmt.Fprintf(w, "%s = %v (%s)\n", k, value, me[k])
+	}
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// writeLayers - writes the named yaml files into a temporary folder
+func writeLayers(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestLayers(t *testing.T) {
+	want := []string{"/etc/app/config.yml", "/etc/app/config.production.yml", "/etc/app/config.override.yml"}
+	if got := Layers("/etc/app", "config", "production"); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	want = []string{"/etc/app/config.yml", "/etc/app/config.override.yml"}
+	if got := Layers("/etc/app", "config", ""); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v without an environment, got %v", want, got)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	for key, want := range map[string]string{
+		"app.ssl.enabled":          "APP_SSL_ENABLED",
+		"app.server.read-timeout":  "APP_SERVER_READ_TIMEOUT",
+		"app.cors.allowed_origins": "APP_CORS_ALLOWED_ORIGINS",
+		"name":                     "NAME",
+	} {
+		if got := EnvName(key); got != want {
+			t.Errorf("%s: expected %s, got %s", key, want, got)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	base := `app:
+  environment: staging
+  name: base
+  log_level: info
+  port: 8080
+  ssl:
+    enabled: false
+`
+	dir := writeLayers(t, map[string]string{
+		"config.yml":            base,
+		"config.staging.yml":    "app:\n  log_level: debug\n  port: 9090\n",
+		"config.production.yml": "app:\n  log_level: error\n",
+		"config.override.yml":   "app:\n  port: 7070\n",
+	})
+	baseFile, stagingFile, overrideFile := filepath.Join(dir, "config.yml"), filepath.Join(dir, "config.staging.yml"), filepath.Join(dir, "config.override.yml")
+
+	t.Setenv("APP_SSL_ENABLED", "true")
+	t.Setenv("APP_TIMEOUT", "5s")
+
+	conf := viper.New()
+	conf.SetDefault("app.timeout", "1s")
+	conf.SetDefault("app.retries", 3)
+
+	sources, files, err := Load(conf, dir, "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{baseFile, stagingFile, overrideFile}; !reflect.DeepEqual(files, want) {
+		t.Errorf("expected the layers %v, got %v", want, files)
+	}
+
+	// each layer overrides the previous one, environment variables override them all
+	tests := []struct {
+		key    string
+		value  interface{}
+		source string
+	}{
+		{"app.name", "base", baseFile},
+		{"app.log_level", "debug", stagingFile},
+		{"app.port", 7070, overrideFile},
+		{"app.ssl.enabled", true, "env:APP_SSL_ENABLED"},
+		{"app.timeout", "5s", "env:APP_TIMEOUT"},
+		{"app.retries", 3, SourceDefault},
+	}
+	for _, tt := range tests {
+		var got interface{}
+		switch tt.value.(type) {
+		case int:
+			got = conf.GetInt(tt.key)
+		case bool:
+			got = conf.GetBool(tt.key)
+		default:
+			got = conf.GetString(tt.key)
+		}
+		if got != tt.value {
+			t.Errorf("%s: expected %v, got %v", tt.key, tt.value, got)
+		}
+		if sources[tt.key] != tt.source {
+			t.Errorf("%s: expected the source %s, got %s", tt.key, tt.source, sources[tt.key])
+		}
+	}
+}
+
+func TestLoadEnvironmentFromEnv(t *testing.T) {
+	dir := writeLayers(t, map[string]string{
+		"config.yml":            "app:\n  environment: staging\n  log_level: info\n",
+		"config.staging.yml":    "app:\n  log_level: debug\n",
+		"config.production.yml": "app:\n  log_level: error\n",
+	})
+	t.Setenv("APP_ENVIRONMENT", "production")
+
+	conf := viper.New()
+	sources, files, err := Load(conf, dir, "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the environment variable selects the environment file, the missing override is skipped
+	if got := conf.GetString("app.log_level"); got != "error" {
+		t.Errorf("expected the production value, got %s", got)
+	}
+	if sources["app.log_level"] != filepath.Join(dir, "config.production.yml") || sources["app.environment"] != "env:APP_ENVIRONMENT" {
+		t.Errorf("unexpected sources %v", sources)
+	}
+	if len(files) != 3 || !strings.HasSuffix(files[1], "config.production.yml") {
+		t.Errorf("unexpected layers %v", files)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		problem string
+	}{
+		{"missing base", map[string]string{"config.override.yml": "app:\n  port: 1\n"}, "failed to read config"},
+		{"invalid base", map[string]string{"config.yml": "app: [unclosed\n"}, "config.yml` with error"},
+		{"invalid optional layer", map[string]string{"config.yml": "app:\n  port: 1\n", "config.override.yml": "app: [unclosed\n"},
+			"config.override.yml` with error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Load(viper.New(), writeLayers(t, tt.files), "config")
+			if err == nil || !strings.Contains(err.Error(), tt.problem) {
+				t.Errorf("expected `%s`, got %v", tt.problem, err)
+			}
+		})
+	}
+}
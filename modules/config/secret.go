@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// SourceSecret prefixes the source of values resolved from a secret reference
+	SourceSecret = "secret:"
+	// Redacted replaces secret values whenever config is logged or dumped
+	Redacted = "[REDACTED]"
+)
+
+var (
+	// secretRef matches ${env:NAME}, ${file:/path} and the forms with a fallback, ${env:NAME:-fallback}
+	secretRef = regexp.MustCompile(`\$\{(env|file):([^}]+)\}`)
+
+	// sensitiveWords - keys whose last segment holds one of these words are redacted whatever their source,
+	// e.g. app.database.password set by APP_DATABASE_PASSWORD or app.oauth.client_secret
+	sensitiveWords = map[string]bool{
+		"password": true,
+		"passwd":   true,
+		"token":    true,
+		"secret":   true,
+	}
+
+	// sensitiveNames - credentials named after a key, matched on the whole last segment or its end,
+	// e.g. app.payments.stripe_api_key. A bare key such as app.ssl.key is a file path and stays readable
+	sensitiveNames = []string{"api_key", "apikey", "private_key", "secret_key"}
+)
+
+// ResolveSecrets replaces the secret references found in string values with the referenced content
+// and marks the keys as secret in sources. All the unresolvable references are reported together.
+func ResolveSecrets(conf *viper.Viper, sources Sources) error {
+	var errs []string
+
+	for _, k := range conf.AllKeys() {
+		value, ok := conf.Get(k).(string)
+		if !ok || !secretRef.MatchString(value) {
+			continue
+		}
+
+		var refs []string
+		resolved := secretRef.ReplaceAllStringFunc(value, func(ref string) string {
+			m := secretRef.FindStringSubmatch(ref)
+			refs = append(refs, m[1]+":"+strings.SplitN(m[2], ":-", 2)[0])
+
+			secret, err := lookupSecret(m[1], m[2])
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", k, err))
+			}
+			return secret
+		})
+
+		conf.Set(k, resolved)
+		sources[k] = SourceSecret + strings.Join(refs, ",")
+	}
+
+	if len(errs) > 0 {
+		return errors.New("failed to resolve secrets: " + strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// lookupSecret reads one reference, the fallback after :- is used when it is missing
+func lookupSecret(kind, ref string) (string, error) {
+	parts := strings.SplitN(ref, ":-", 2)
+	name := parts[0]
+
+	switch kind {
+	case "env":
+		if value, ok := os.LookupEnv(name); ok {
+			return value, nil
+		}
+	case "file":
+		if b, err := ioutil.ReadFile(name); err == nil {
+			return strings.TrimRight(string(b), "\r\n"), nil
+		} else if len(parts) == 1 {
+			return "", err
+		}
+	}
+
+	if len(parts) == 2 {
+		return parts[1], nil
+	}
+
+	return "", fmt.Errorf("environment variable %s is not set", name)
+}
+
+// IsSecret reports whether the value of key was resolved from a secret reference
+func (me Sources) IsSecret(key string) bool {
+	return strings.HasPrefix(me[key], SourceSecret)
+}
+
+// IsSensitive reports whether the name of key marks it as a credential
+func IsSensitive(key string) bool {
+	name := strings.ReplaceAll(strings.ToLower(key[strings.LastIndex(key, ".")+1:]), "-", "_")
+	for _, credential := range sensitiveNames {
+		if name == credential || strings.HasSuffix(name, "_"+credential) {
+			return true
+		}
+	}
+
+	for _, word := range strings.Split(name, "_") {
+		if sensitiveWords[word] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Display returns the value of key safe for logs, secret references and sensitive keys are redacted
+func (me Sources) Display(key string, value interface{}) interface{} {
+	if me.IsSecret(key) || IsSensitive(key) {
+		return Redacted
+	}
+
+	return value
+}
+
+// Redact returns the nested settings of conf with the secret values replaced
+func Redact(conf *viper.Viper, sources Sources) map[string]interface{} {
+	settings := make(map[string]interface{})
+
+	for _, k := range conf.AllKeys() {
+		path := strings.Split(k, ".")
+		m := settings
+		for _, p := range path[:len(path)-1] {
+			next, ok := m[p].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				m[p] = next
+			}
+			m = next
+		}
+		m[path[len(path)-1]] = sources.Display(k, conf.Get(k))
+	}
+
+	return settings
+}
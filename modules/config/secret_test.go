@@ -0,0 +1,142 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "db_password")
+	os.WriteFile(secretFile, []byte("from-file\n"), 0600)
+	t.Setenv("TEST_TOKEN", "from-env")
+
+	conf := viper.New()
+	sources, err := LoadMap(conf, map[string]interface{}{
+		"app": map[string]interface{}{
+			"token":    "${env:TEST_TOKEN}",
+			"password": "${file:" + secretFile + "}",
+			"dsn":      "user:${env:TEST_MISSING:-fallback}@host",
+			"plain":    "value",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]string{
+		"app.token":    "from-env",
+		"app.password": "from-file",
+		"app.dsn":      "user:fallback@host",
+		"app.plain":    "value",
+	} {
+		if got := conf.GetString(key); got != want {
+			t.Errorf("%s: expected %s, got %s", key, want, got)
+		}
+	}
+
+	if !sources.IsSecret("app.dsn") || sources.IsSecret("app.plain") {
+		t.Errorf("unexpected sources %v", sources)
+	}
+	if sources["app.token"] != SourceSecret+"env:TEST_TOKEN" {
+		t.Errorf("unexpected source %s", sources["app.token"])
+	}
+
+	_, err = LoadMap(viper.New(), map[string]interface{}{
+		"app": map[string]interface{}{
+			"a": "${env:TEST_MISSING}",
+			"b": "${file:/nonexistent/secret}",
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "app.a: environment variable TEST_MISSING is not set") ||
+		!strings.Contains(err.Error(), "app.b:") {
+		t.Errorf("expected every unresolved reference to be reported, got %v", err)
+	}
+}
+
+func TestIsSensitive(t *testing.T) {
+	for key, want := range map[string]bool{
+		"app.database.password":       true,
+		"app.admin.token":             true,
+		"app.payments.api_key":        true,
+		"app.payments.stripe-api-key": true,
+		"app.maps.apikey":             true,
+		"app.jwt.private_key":         true,
+		"app.aws.secret_key":          true,
+		"app.ssl.key":                 false,
+		"app.ssl.client_key":          false,
+		"app.cache.key":               false,
+		"app.jwt.public_key":          false,
+		"app.aws.access_key_id":       false,
+		"app.oauth.client-secret":     true,
+		"app.smtp.passwd":             true,
+		"app.database.user":           false,
+		"app.server.keep_alive":       false,
+		"app.cache.keys_prefix":       false,
+		"app.password_policy.size":    false,
+	} {
+		if got := IsSensitive(key); got != want {
+			t.Errorf("%s: expected %v, got %v", key, want, got)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	t.Setenv("APP_DATABASE_PASSWORD", "plain-env-password")
+	t.Setenv("TEST_REF", "referenced")
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "config.yml"), []byte(`app:
+  name: test
+  database:
+    user: root
+    password: from-file
+  cache:
+    server: ${env:TEST_REF}
+  ssl:
+    key: ./server.key
+`), 0644)
+
+	conf := viper.New()
+	sources, _, err := Load(conf, dir, "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.GetString("app.database.password") != "plain-env-password" {
+		t.Fatal("expected the env override to be applied")
+	}
+
+	redacted := Redact(conf, sources)
+	app := redacted["app"].(map[string]interface{})
+	if got := app["database"].(map[string]interface{})["password"]; got != Redacted {
+		t.Errorf("expected the env override of a password to be redacted, got %v", got)
+	}
+	if got := app["database"].(map[string]interface{})["user"]; got != "root" {
+		t.Errorf("expected the user in clear, got %v", got)
+	}
+	if got := app["ssl"].(map[string]interface{})["key"]; got != "./server.key" {
+		t.Errorf("expected the key file path in clear, got %v", got)
+	}
+	if got := app["cache"].(map[string]interface{})["server"]; got != Redacted {
+		t.Errorf("expected a secret reference to be redacted, got %v", got)
+	}
+
+	var out bytes.Buffer
+	sources.Print(&out, conf, true)
+	if strings.Contains(out.String(), "plain-env-password") || strings.Contains(out.String(), "referenced") {
+		t.Errorf("secret printed in clear:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "app.database.password = [REDACTED] (env:APP_DATABASE_PASSWORD)") {
+		t.Errorf("expected the redacted password with its source:\n%s", out.String())
+	}
+
+	out.Reset()
+	sources.Print(&out, conf, false)
+	if !strings.Contains(out.String(), "plain-env-password") {
+		t.Errorf("expected the values in clear without redaction:\n%s", out.String())
+	}
+}
@@ -0,0 +1,342 @@
+package connlimit
+
+import (
+	"errors"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"httpframwork/modules/container"
+)
+
+type (
+	// Limiter caps the connections accepted by the listeners it wraps, globally and per client IP
+	Limiter struct {
+		sync.Mutex
+		max          int
+		perIP        int
+		queue        bool
+		queueTimeout time.Duration
+		maxQueued    int
+		active       int
+		queued       int
+		accepted     uint64
+		rejected     uint64
+		clients      map[string]int
+		released     *sync.Cond
+	}
+
+	// Stats is a snapshot of the limiter counters
+	Stats struct {
+		Max      int            `json:"max"`
+		PerIP    int            `json:"per_ip"`
+		Mode     string         `json:"mode"`
+		Active   int            `json:"active"`
+		Queued   int            `json:"queued"`
+		Accepted uint64         `json:"accepted"`
+		Rejected uint64         `json:"rejected"`
+		Clients  map[string]int `json:"clients,omitempty"`
+	}
+
+	// listener hands out the connections admitted by the limiter
+	listener struct {
+		net.Listener
+		limiter *Limiter
+		conns   chan net.Conn
+		errs    chan error
+		done    chan struct{}
+		start   sync.Once
+		close   sync.Once
+	}
+
+	// conn releases its slot when it is closed
+	conn struct {
+		net.Conn
+		limiter *Limiter
+		ip      string
+		release sync.Once
+	}
+)
+
+const (
+	InstanceKey = "ConnLimit"
+
+	ModeReject = "reject"
+	ModeQueue  = "queue"
+
+	// maxClients bounds the clients listed in Stats, the busiest come first
+	maxClients = 100
+
+	// accept retries back off between these delays
+	minRetryDelay = 5 * time.Millisecond
+	maxRetryDelay = time.Second
+)
+
+// GetRegistry function ...
+func GetRegistry() container.Registries {
+	obj := &Limiter{
+		clients: make(map[string]int),
+	}
+	obj.released = sync.NewCond(&obj.Mutex)
+
+	return container.Registries{
+		container.Registry{
+			Key:   InstanceKey,
+			Value: obj,
+		},
+	}
+}
+
+// GetInstance function ...
+func GetInstance(c *container.Container) *Limiter {
+	return c.Get(InstanceKey).(*Limiter)
+}
+
+// Configure method - sets the limits, zero means unlimited. In queue mode connections over a
+// limit wait up to queueTimeout for a free slot, at most maxQueued at once, before they are closed.
+func (me *Limiter) Configure(max, perIP int, mode string, queueTimeout time.Duration, maxQueued int) *Limiter {
+	me.Lock()
+	defer me.Unlock()
+
+	me.max = max
+	me.perIP = perIP
+	me.queue = mode == ModeQueue
+	me.queueTimeout = queueTimeout
+	me.maxQueued = maxQueued
+
+	return me
+}
+
+// Wrap method - returns a listener admitting connections within the limits
+func (me *Limiter) Wrap(ln net.Listener) net.Listener {
+	return &listener{
+		Listener: ln,
+		limiter:  me,
+		conns:    make(chan net.Conn),
+		errs:     make(chan error, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+// Counts method - returns the current counters without the clients
+func (me *Limiter) Counts() Stats {
+	me.Lock()
+	defer me.Unlock()
+
+	return me.counts()
+}
+
+// Stats method - returns the current counters with the busiest clients
+func (me *Limiter) Stats() Stats {
+	me.Lock()
+	defer me.Unlock()
+
+	s := me.counts()
+	s.Clients = make(map[string]int)
+
+	ips := make([]string, 0, len(me.clients))
+	for ip := range me.clients {
+		ips = append(ips, ip)
+	}
+	sort.Slice(ips, func(i, j int) bool { return me.clients[ips[i]] > me.clients[ips[j]] })
+	if len(ips) > maxClients {
+		ips = ips[:maxClients]
+	}
+	for _, ip := range ips {
+		s.Clients[ip] = me.clients[ip]
+	}
+
+	return s
+}
+
+// counts - the lock must be held
+func (me *Limiter) counts() Stats {
+	s := Stats{
+		Max:      me.max,
+		PerIP:    me.perIP,
+		Mode:     ModeReject,
+		Active:   me.active,
+		Queued:   me.queued,
+		Accepted: me.accepted,
+		Rejected: me.rejected,
+	}
+	if me.queue {
+		s.Mode = ModeQueue
+	}
+
+	return s
+}
+
+// fits - tells whether a connection from ip is within the limits, the lock must be held
+func (me *Limiter) fits(ip string) bool {
+	if me.max > 0 && me.active >= me.max {
+		return false
+	}
+
+	return me.perIP <= 0 || ip == "" || me.clients[ip] < me.perIP
+}
+
+// acquire - takes a slot for ip, in queue mode it waits for one until the timeout or done
+func (me *Limiter) acquire(ip string, done <-chan struct{}) bool {
+	me.Lock()
+	defer me.Unlock()
+
+	if !me.fits(ip) {
+		if !me.queue || me.queued >= me.maxQueued {
+			me.rejected++
+			return false
+		}
+
+		me.queued++
+		expired := false
+		timer := time.AfterFunc(me.queueTimeout, func() {
+			me.Lock()
+			expired = true
+			me.Unlock()
+			me.released.Broadcast()
+		})
+		stop := make(chan struct{})
+		go func() {
+			select {
+			case <-done:
+				me.Lock()
+				expired = true
+				me.Unlock()
+				me.released.Broadcast()
+			case <-stop:
+			}
+		}()
+
+		for !me.fits(ip) && !expired {
+			me.released.Wait()
+		}
+		timer.Stop()
+		close(stop)
+		me.queued--
+
+		if !me.fits(ip) {
+			me.rejected++
+			return false
+		}
+	}
+
+	me.active++
+	me.accepted++
+	if ip != "" {
+		me.clients[ip]++
+	}
+
+	return true
+}
+
+// release - frees the slot of a closed connection
+func (me *Limiter) release(ip string) {
+	me.Lock()
+	me.active--
+	if ip != "" {
+		if me.clients[ip]--; me.clients[ip] <= 0 {
+			delete(me.clients, ip)
+		}
+	}
+	me.Unlock()
+
+	me.released.Broadcast()
+}
+
+// Accept - returns the next admitted connection
+func (me *listener) Accept() (net.Conn, error) {
+	me.start.Do(func() { go me.run() })
+
+	select {
+	case c := <-me.conns:
+		return c, nil
+	case err := <-me.errs:
+		// keep the error for the following calls
+		me.errs <- err
+		return nil, err
+	case <-me.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close - stops accepting and closes the wrapped listener
+func (me *listener) Close() (err error) {
+	err = net.ErrClosed
+	me.close.Do(func() {
+		close(me.done)
+		err = me.Listener.Close()
+	})
+
+	return
+}
+
+// Unwrap - returns the wrapped listener, e.g. to hand its descriptor over
+func (me *listener) Unwrap() net.Listener {
+	return me.Listener
+}
+
+// run - accepts connections and admits them, connections waiting for a slot don't block the others.
+// Temporary errors, e.g. running out of file descriptors, are retried with a backoff as net/http does.
+func (me *listener) run() {
+	var delay time.Duration
+	for {
+		c, err := me.Listener.Accept()
+		if err != nil {
+			var ne net.Error
+			if !errors.As(err, &ne) || !(ne.Timeout() || ne.Temporary()) {
+				me.errs <- err
+				return
+			}
+
+			if delay = 2 * delay; delay == 0 {
+				delay = minRetryDelay
+			} else if delay > maxRetryDelay {
+				delay = maxRetryDelay
+			}
+			log.Printf("Accept error `%v`, retrying in %s\n", err, delay)
+
+			select {
+			case <-time.After(delay):
+			case <-me.done:
+				return
+			}
+			continue
+		}
+
+		delay = 0
+		go me.admit(c)
+	}
+}
+
+// admit - delivers the connection once it has a slot, closes it otherwise
+func (me *listener) admit(c net.Conn) {
+	ip := clientIP(c.RemoteAddr())
+	if !me.limiter.acquire(ip, me.done) {
+		c.Close()
+		return
+	}
+
+	lc := &conn{Conn: c, limiter: me.limiter, ip: ip}
+	select {
+	case me.conns <- lc:
+	case <-me.done:
+		lc.Close()
+	}
+}
+
+// Close - closes the connection and frees its slot
+func (me *conn) Close() error {
+	me.release.Do(func() { me.limiter.release(me.ip) })
+	return me.Conn.Close()
+}
+
+// clientIP - returns the IP of tcp peers, unix socket peers share no limit
+func clientIP(addr net.Addr) string {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP.String()
+	}
+
+	return ""
+}
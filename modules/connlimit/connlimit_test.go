@@ -0,0 +1,260 @@
+package connlimit
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newLimiter - returns a limiter as registered in the container
+func newLimiter() *Limiter {
+	return GetRegistry()[0].Value.(*Limiter)
+}
+
+// serve - wraps a loopback listener and returns the admitted connections on a channel
+func serve(t *testing.T, limiter *Limiter) (net.Listener, <-chan net.Conn) {
+	t.Helper()
+
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := limiter.Wrap(tcp)
+	t.Cleanup(func() { ln.Close() })
+
+	accepted := make(chan net.Conn, 8)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	return ln, accepted
+}
+
+// dial - connects to the listener
+func dial(t *testing.T, ln net.Listener) net.Conn {
+	t.Helper()
+
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	return c
+}
+
+// expectAccepted - waits for the next admitted connection
+func expectAccepted(t *testing.T, accepted <-chan net.Conn) net.Conn {
+	t.Helper()
+
+	select {
+	case c := <-accepted:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not admitted")
+		return nil
+	}
+}
+
+// expectClosed - checks the server closed the client connection
+func expectClosed(t *testing.T, c net.Conn) {
+	t.Helper()
+
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected the connection to be closed, got %v", err)
+	}
+}
+
+func TestRejectMode(t *testing.T) {
+	limiter := newLimiter().Configure(1, 0, ModeReject, 0, 0)
+	ln, accepted := serve(t, limiter)
+
+	dial(t, ln)
+	first := expectAccepted(t, accepted)
+
+	// over the limit the connection is closed right away
+	expectClosed(t, dial(t, ln))
+	if s := limiter.Counts(); s.Active != 1 || s.Accepted != 1 || s.Rejected != 1 || s.Mode != ModeReject {
+		t.Errorf("unexpected counters %+v", s)
+	}
+
+	// closing a connection frees its slot, closing it twice frees it once
+	first.Close()
+	first.Close()
+	dial(t, ln)
+	expectAccepted(t, accepted)
+	if s := limiter.Counts(); s.Active != 1 || s.Accepted != 2 {
+		t.Errorf("unexpected counters %+v", s)
+	}
+}
+
+func TestPerIPLimit(t *testing.T) {
+	limiter := newLimiter().Configure(0, 2, ModeReject, 0, 0)
+	ln, accepted := serve(t, limiter)
+
+	for i := 0; i < 2; i++ {
+		dial(t, ln)
+		expectAccepted(t, accepted)
+	}
+	expectClosed(t, dial(t, ln))
+
+	if s := limiter.Stats(); s.Clients["127.0.0.1"] != 2 || s.Rejected != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestQueueMode(t *testing.T) {
+	limiter := newLimiter().Configure(1, 0, ModeQueue, 5*time.Second, 1)
+	ln, accepted := serve(t, limiter)
+
+	dial(t, ln)
+	first := expectAccepted(t, accepted)
+
+	// the second connection waits for the slot, the third finds the queue full
+	dial(t, ln)
+	deadline := time.Now().Add(5 * time.Second)
+	for limiter.Counts().Queued != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("connection not queued")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	expectClosed(t, dial(t, ln))
+
+	first.Close()
+	expectAccepted(t, accepted)
+	if s := limiter.Counts(); s.Active != 1 || s.Queued != 0 || s.Accepted != 2 || s.Rejected != 1 || s.Mode != ModeQueue {
+		t.Errorf("unexpected counters %+v", s)
+	}
+}
+
+func TestQueueTimeout(t *testing.T) {
+	limiter := newLimiter().Configure(1, 0, ModeQueue, 50*time.Millisecond, 10)
+	ln, accepted := serve(t, limiter)
+
+	dial(t, ln)
+	expectAccepted(t, accepted)
+
+	start := time.Now()
+	expectClosed(t, dial(t, ln))
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("connection closed after %s, before the queue timeout", elapsed)
+	}
+	if s := limiter.Counts(); s.Queued != 0 || s.Rejected != 1 {
+		t.Errorf("unexpected counters %+v", s)
+	}
+}
+
+// fakeListener - returns the results sent by the test from Accept
+type fakeListener struct {
+	results chan interface{}
+	closed  chan struct{}
+}
+
+// newFakeListener - returns a listener that already holds the results
+func newFakeListener(results ...interface{}) *fakeListener {
+	ln := &fakeListener{results: make(chan interface{}, len(results)+1), closed: make(chan struct{})}
+	for _, r := range results {
+		ln.results <- r
+	}
+
+	return ln
+}
+
+func (me *fakeListener) Accept() (net.Conn, error) {
+	select {
+	case r := <-me.results:
+		if err, ok := r.(error); ok {
+			return nil, err
+		}
+		return r.(net.Conn), nil
+	case <-me.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (me *fakeListener) Close() error {
+	close(me.closed)
+	return nil
+}
+
+func (me *fakeListener) Addr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+}
+
+// emfile - the error returned by accept when the process runs out of file descriptors
+var emfile = &net.OpError{Op: "accept", Net: "tcp", Err: os.NewSyscallError("accept", syscall.EMFILE)}
+
+func TestTemporaryAcceptErrors(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	fake := newFakeListener(emfile, emfile, emfile, server)
+	ln := newLimiter().Wrap(fake)
+	defer ln.Close()
+
+	// running out of descriptors does not stop accepting
+	start := time.Now()
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("expected the connection after the temporary errors, got %v", err)
+	}
+	c.Close()
+	if elapsed := time.Since(start); elapsed < (5+10+20)*time.Millisecond {
+		t.Errorf("retried without backing off, after %s", elapsed)
+	}
+
+	// a permanent error is reported to every caller
+	permanent := errors.New("listener broken")
+	fake.results <- permanent
+	for i := 0; i < 2; i++ {
+		if _, err = ln.Accept(); err != permanent {
+			t.Fatalf("expected the permanent error, got %v", err)
+		}
+	}
+}
+
+func TestCloseDuringBackoff(t *testing.T) {
+	fake := newFakeListener()
+	ln := newLimiter().Wrap(fake)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := ln.Accept()
+		errs <- err
+	}()
+
+	// the listener keeps failing until it is closed
+	go func() {
+		for {
+			select {
+			case fake.results <- emfile:
+			case <-fake.closed:
+				return
+			}
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	ln.Close()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Errorf("expected the closed listener error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Accept still blocked after Close")
+	}
+}
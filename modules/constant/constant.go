@@ -1,21 +1,185 @@
 package constant
 
+import "time"
+
 const (
 	EnvConfigPath    = "CONFIG_PATH"
 	EnvErrorFilePath = "ERROR_LANG"
+	EnvConfigDebug   = "CONFIG_DEBUG"
 )
 
 const (
-	DefaultConfigPath = "/configs"
-	DefaultDateTimeFormat = "2006-01-02 15:04:05"
+	DefaultConfigPath           = "/configs"
+	DefaultDateTimeFormat       = "2006-01-02 15:04:05"
+	DefaultShutdownTimeout      = 30 * time.Second
+	DefaultReadTimeout          = 30 * time.Second
+	DefaultReadHeaderTimeout    = 10 * time.Second
+	DefaultWriteTimeout         = 60 * time.Second
+	DefaultIdleTimeout          = 120 * time.Second
+	DefaultMaxHeaderBytes       = 1 << 20
+	DefaultListenNetwork        = "tcp"
+	DefaultSocketMode           = "0660"
+	DefaultHSTSMaxAge           = 365 * 24 * 60 * 60
+	DefaultSSLReloadInterval    = time.Minute
+	DefaultSSLPort              = "443"
+	DefaultSelfSignedDir        = "./certs"
+	DefaultSelfSignedTTL        = 90 * 24 * time.Hour
+	EnvironmentLocal            = "local"
+	DefaultHTTP2MaxStreams      = 250
+	DefaultHTTP2MaxFrameSize    = 1 << 20
+	DefaultUpgradeTimeout       = 30 * time.Second
+	DefaultLogLevel             = "trace"
+	DefaultConfigReloadInterval = 5 * time.Second
+	DefaultHealthTimeout        = 2 * time.Second
+	DefaultHealthCacheTTL       = time.Second
+	DefaultAdminHost            = "127.0.0.1"
+	DefaultAdminPort            = 9090
+	DefaultMetricsPath          = "/metrics"
+	DefaultStaticPath           = "/"
+	DefaultStaticDir            = "./public"
+	DefaultConnectionsMode      = "reject"
+	DefaultQueueTimeout         = 5 * time.Second
+	DefaultMaxQueued            = 1024
+	DefaultTracingExporter      = "file"
+	DefaultTracingFile          = "/var/log/gohttp/traces.json"
+	DefaultTracingEndpoint      = "http://localhost:4318/v1/traces"
+	DefaultTracingInterval      = 5 * time.Second
+	DefaultTracingBatchSize     = 512
+	DefaultTracingTimeout       = 10 * time.Second
 )
 
 // Application Config keys
 const (
-	SSLEnabled      = "app.ssl.enabled"
-	SSLCertFilePath = "app.ssl.cert"
-	SSLKeyPath      = "app.ssl.key"
-	AppDomain       = "app.domain"
-	AppEnvironment  = "app.environment"
-	AppLogFolder    = "app.app_log"
+	SSLEnabled           = "app.ssl.enabled"
+	SSLCertFilePath      = "app.ssl.cert"
+	SSLKeyPath           = "app.ssl.key"
+	SSLHost              = "app.ssl.host"
+	SSLPort              = "app.ssl.port"
+	SSLNetwork           = "app.ssl.network"
+	SSLSocket            = "app.ssl.socket"
+	SSLSocketMode        = "app.ssl.socket_mode"
+	SSLFDName            = "app.ssl.fd_name"
+	SSLReloadInterval    = "app.ssl.reload_interval"
+	SSLClientCA          = "app.ssl.client_ca"
+	SSLClientAuth        = "app.ssl.client_auth"
+	SSLSelfSignedPersist = "app.ssl.self_signed.persist"
+	SSLSelfSignedDir     = "app.ssl.self_signed.dir"
+	HSTSMaxAge           = "app.ssl.hsts.max_age"
+	HSTSSubdomains       = "app.ssl.hsts.include_subdomains"
+	AppDomain            = "app.domain"
+	AppEnvironment       = "app.environment"
+	AppLogFolder         = "app.app_log"
+	AppLogLevel          = "app.log_level"
+	CORSAllowedOrigins   = "app.cors.allowed_origins"
+	ConfigReloadInterval = "app.config.reload_interval"
+)
+
+// DefaultAllowedOrigins allows cross-origin requests from anywhere
+var DefaultAllowedOrigins = []string{"*"}
+
+// Server config keys
+const (
+	ServerShutdownTimeout     = "app.server.shutdown_timeout"
+	ServerShutdownDelay       = "app.server.shutdown_delay"
+	ServerReadTimeout         = "app.server.read_timeout"
+	ServerReadHeaderTimeout   = "app.server.read_header_timeout"
+	ServerWriteTimeout        = "app.server.write_timeout"
+	ServerIdleTimeout         = "app.server.idle_timeout"
+	ServerMaxHeaderBytes      = "app.server.max_header_bytes"
+	ServerKeepAlivesEnabled   = "app.server.keep_alive"
+	HTTP2Enabled              = "app.server.http2.enabled"
+	HTTP2Cleartext            = "app.server.http2.h2c"
+	HTTP2MaxConcurrentStreams = "app.server.http2.max_concurrent_streams"
+	HTTP2MaxReadFrameSize     = "app.server.http2.max_read_frame_size"
+	ConnectionsMax            = "app.server.connections.max"
+	ConnectionsPerIP          = "app.server.connections.per_ip"
+	ConnectionsMode           = "app.server.connections.mode"
+	ConnectionsQueueTimeout   = "app.server.connections.queue_timeout"
+	ConnectionsMaxQueued      = "app.server.connections.max_queued"
+)
+
+// Binary upgrade config keys
+const (
+	UpgradeEnabled      = "app.upgrade.enabled"
+	UpgradeReadyTimeout = "app.upgrade.ready_timeout"
+)
+
+// Health check config keys
+const (
+	HealthTimeout  = "app.health.timeout"
+	HealthCacheTTL = "app.health.cache_ttl"
+)
+
+// Metrics config keys
+const (
+	MetricsEnabled = "app.metrics.enabled"
+	MetricsPath    = "app.metrics.path"
+	MetricsPublic  = "app.metrics.public"
+)
+
+// Tracing config keys
+const (
+	TracingEnabled       = "app.tracing.enabled"
+	TracingServiceName   = "app.tracing.service_name"
+	TracingSampleRatio   = "app.tracing.sample_ratio"
+	TracingExporter      = "app.tracing.exporter"
+	TracingFile          = "app.tracing.file"
+	TracingEndpoint      = "app.tracing.endpoint"
+	TracingFlushInterval = "app.tracing.flush_interval"
+	TracingBatchSize     = "app.tracing.batch_size"
+	TracingTimeout       = "app.tracing.timeout"
+	AppName              = "app.name"
+)
+
+// Rate limit config keys
+const (
+	RateLimitRate         = "app.ratelimit.rate"
+	RateLimitBurst        = "app.ratelimit.burst"
+	DefaultRateLimitBurst = 20
+)
+
+// Feature flags config key, a map of flag names to booleans
+const (
+	Features = "app.features"
+)
+
+// Static files config keys
+const (
+	StaticEnabled = "app.static.enabled"
+	StaticPath    = "app.static.path"
+	StaticDir     = "app.static.dir"
+	StaticSPA     = "app.static.spa"
+)
+
+// Scheduler config keys
+const (
+	SchedulerEnabled      = "app.scheduler.enabled"
+	SchedulerTimezone     = "app.scheduler.timezone"
+	LogCleanupSpec        = "app.scheduler.log_cleanup.spec"
+	LogCleanupRetention   = "app.scheduler.log_cleanup.retention"
+	DefaultLogCleanupSpec = "@hourly"
+)
+
+// Admin server config keys
+const (
+	AdminEnabled    = "app.admin.enabled"
+	AdminHost       = "app.admin.host"
+	AdminPort       = "app.admin.port"
+	AdminNetwork    = "app.admin.network"
+	AdminSocket     = "app.admin.socket"
+	AdminSocketMode = "app.admin.socket_mode"
+	AdminFDName     = "app.admin.fd_name"
+	AdminToken      = "app.admin.token"
+)
+
+// Listener config keys
+const (
+	ListenEnabled    = "app.listen.enabled"
+	ListenRedirect   = "app.listen.redirect"
+	ListenHost       = "app.listen.host"
+	ListenPort       = "app.listen.port"
+	ListenNetwork    = "app.listen.network"
+	ListenSocket     = "app.listen.socket"
+	ListenSocketMode = "app.listen.socket_mode"
+	ListenFDName     = "app.listen.fd_name"
 )
@@ -71,6 +71,31 @@ func (me *errorsCache) GetError(errorCode string) Error {
 	return Error{}
 }
 
+// GetAll method - returns a copy of every stored error keyed by error code
+func (me *errorsCache) GetAll() ErrorsConfig {
+	me.Lock()
+	defer me.Unlock()
+
+	all := make(ErrorsConfig, len(me.bag))
+	for code, e := range me.bag {
+		all[code] = e
+	}
+
+	return all
+}
+
+// Set method - replaces the stored errors, e.g. with a catalog built in code
+func (me *errorsCache) Set(bag ErrorsConfig) {
+	newBag := make(ErrorsConfig, len(bag))
+	for code, e := range bag {
+		newBag[code] = e
+	}
+
+	me.Lock()
+	me.bag = newBag
+	me.Unlock()
+}
+
 // RetrieveErrors method ... Error storage
 func (me *errorsCache) RetrieveErrors() (err error) {
 
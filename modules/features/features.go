@@ -0,0 +1,74 @@
+package features
+
+import (
+	"sort"
+	"sync"
+
+	"httpframwork/modules/container"
+)
+
+// Flags holds the feature flags of app.features, they follow config reloads
+type Flags struct {
+	sync.RWMutex
+	flags map[string]bool
+}
+
+const (
+	InstanceKey = "Features"
+)
+
+// GetRegistry function ...
+func GetRegistry() container.Registries {
+	obj := &Flags{
+		flags: make(map[string]bool),
+	}
+
+	return container.Registries{
+		container.Registry{
+			Key:   InstanceKey,
+			Value: obj,
+		},
+	}
+}
+
+// GetInstance function ...
+func GetInstance(c *container.Container) *Flags {
+	return c.Get(InstanceKey).(*Flags)
+}
+
+// Set method - replaces every flag, flags missing from the map are off
+func (me *Flags) Set(flags map[string]bool) *Flags {
+	next := make(map[string]bool, len(flags))
+	for name, on := range flags {
+		next[name] = on
+	}
+
+	me.Lock()
+	me.flags = next
+	me.Unlock()
+
+	return me
+}
+
+// Enabled method - tells whether the flag is on, unknown flags are off
+func (me *Flags) Enabled(name string) bool {
+	me.RLock()
+	defer me.RUnlock()
+
+	return me.flags[name]
+}
+
+// List method - returns the names of the flags that are on, sorted
+func (me *Flags) List() (names []string) {
+	me.RLock()
+	defer me.RUnlock()
+
+	for name, on := range me.flags {
+		if on {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return
+}
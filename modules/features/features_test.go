@@ -0,0 +1,31 @@
+package features
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlags(t *testing.T) {
+	flags := GetRegistry()[0].Value.(*Flags)
+
+	if flags.Enabled("beta") {
+		t.Error("unknown flags must be off")
+	}
+
+	initial := map[string]bool{"beta": true, "legacy": false, "alpha": true}
+	flags.Set(initial)
+	initial["legacy"] = true
+
+	if !flags.Enabled("beta") || flags.Enabled("legacy") {
+		t.Error("unexpected flags, the given map must be copied")
+	}
+	if got := flags.List(); !reflect.DeepEqual(got, []string{"alpha", "beta"}) {
+		t.Errorf("expected alpha and beta, got %v", got)
+	}
+
+	// flags missing from a reload are off
+	flags.Set(map[string]bool{"legacy": true})
+	if flags.Enabled("beta") || !flags.Enabled("legacy") {
+		t.Error("expected the flags to be replaced")
+	}
+}
@@ -0,0 +1,247 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"httpframwork/modules/container"
+)
+
+type (
+	// CheckFunc reports a problem with a dependency, ctx carries the check timeout
+	CheckFunc func(ctx context.Context) error
+
+	// Check is a named probe registered by a module
+	Check struct {
+		Name string
+		// Timeout bounds one run, zero uses the registry timeout
+		Timeout time.Duration
+		// Critical failures make the application unready, other failures only degrade it
+		Critical bool
+		// Liveness checks are reported on /livez as well, keep them free of external dependencies
+		Liveness bool
+		Func     CheckFunc
+	}
+
+	// Result is the outcome of one check run
+	Result struct {
+		Name      string    `json:"name"`
+		Status    string    `json:"status"`
+		Critical  bool      `json:"critical"`
+		LatencyMs float64   `json:"latency_ms"`
+		Error     string    `json:"error,omitempty"`
+		CheckedAt time.Time `json:"checked_at"`
+	}
+
+	// Report aggregates the results of the checks run for a probe
+	Report struct {
+		Status string   `json:"status"`
+		Checks []Result `json:"checks"`
+	}
+
+	// entry keeps the last result of a check so probes don't hammer dependencies
+	entry struct {
+		sync.Mutex
+		check  Check
+		result Result
+	}
+
+	// Registry holds the checks registered by the application modules
+	Registry struct {
+		sync.RWMutex
+		entries      []*entry
+		timeout      time.Duration
+		cacheTTL     time.Duration
+		shuttingDown int32
+	}
+)
+
+const (
+	InstanceKey = "Health"
+
+	StatusPass     = "pass"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+
+	DefaultTimeout  = 2 * time.Second
+	DefaultCacheTTL = time.Second
+)
+
+// GetRegistry function ...
+func GetRegistry() container.Registries {
+	obj := &Registry{
+		timeout:  DefaultTimeout,
+		cacheTTL: DefaultCacheTTL,
+	}
+
+	return container.Registries{
+		container.Registry{
+			Key:   InstanceKey,
+			Value: obj,
+		},
+	}
+}
+
+// GetInstance function ...
+func GetInstance(c *container.Container) *Registry {
+	return c.Get(InstanceKey).(*Registry)
+}
+
+// Configure method - sets the default check timeout and how long results are reused
+func (me *Registry) Configure(timeout, cacheTTL time.Duration) *Registry {
+	me.Lock()
+	defer me.Unlock()
+
+	if timeout > 0 {
+		me.timeout = timeout
+	}
+	me.cacheTTL = cacheTTL
+
+	return me
+}
+
+// Register method - adds a check, a check registered twice under the same name is replaced
+func (me *Registry) Register(check Check) *Registry {
+	me.Lock()
+	defer me.Unlock()
+
+	for _, e := range me.entries {
+		if e.check.Name == check.Name {
+			e.Lock()
+			e.check, e.result = check, Result{}
+			e.Unlock()
+			return me
+		}
+	}
+	me.entries = append(me.entries, &entry{check: check})
+
+	return me
+}
+
+// Shutdown method - makes readiness fail so load balancers stop sending traffic
+func (me *Registry) Shutdown() {
+	atomic.StoreInt32(&me.shuttingDown, 1)
+}
+
+// ShuttingDown method - tells whether the application is shutting down
+func (me *Registry) ShuttingDown() bool {
+	return atomic.LoadInt32(&me.shuttingDown) == 1
+}
+
+// Live method - runs the liveness checks
+func (me *Registry) Live(ctx context.Context) Report {
+	return me.run(ctx, true)
+}
+
+// Ready method - runs every check, readiness fails once the application is shutting down
+func (me *Registry) Ready(ctx context.Context) Report {
+	report := me.run(ctx, false)
+	if me.ShuttingDown() {
+		report.Status = StatusFail
+		report.Checks = append(report.Checks, Result{
+			Name:      "shutdown",
+			Status:    StatusFail,
+			Critical:  true,
+			Error:     "application is shutting down",
+			CheckedAt: time.Now().UTC(),
+		})
+	}
+
+	return report
+}
+
+// Healthy method - a degraded report is still healthy
+func (me Report) Healthy() bool {
+	return me.Status != StatusFail
+}
+
+// run - runs the selected checks concurrently and aggregates their results
+func (me *Registry) run(ctx context.Context, liveness bool) Report {
+	me.RLock()
+	timeout, ttl := me.timeout, me.cacheTTL
+	var entries []*entry
+	for _, e := range me.entries {
+		if !liveness || e.check.Liveness {
+			entries = append(entries, e)
+		}
+	}
+	me.RUnlock()
+
+	report := Report{Status: StatusPass, Checks: make([]Result, len(entries))}
+
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		wg.Add(1)
+		go func(i int, e *entry) {
+			defer wg.Done()
+			report.Checks[i] = e.run(ctx, timeout, ttl)
+		}(i, e)
+	}
+	wg.Wait()
+
+	for _, r := range report.Checks {
+		if r.Status == StatusPass {
+			continue
+		}
+		if r.Critical {
+			report.Status = StatusFail
+		} else if report.Status == StatusPass {
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+// run - returns the cached result while it is fresh, otherwise runs the check.
+// Concurrent probes wait for the run in progress instead of starting their own.
+func (me *entry) run(ctx context.Context, timeout, ttl time.Duration) Result {
+	me.Lock()
+	defer me.Unlock()
+
+	if !me.result.CheckedAt.IsZero() && time.Since(me.result.CheckedAt) < ttl {
+		return me.result
+	}
+
+	if me.check.Timeout > 0 {
+		timeout = me.check.Timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- me.check.Func(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// checks ignoring ctx are abandoned once the timeout expires
+		err = fmt.Errorf("check timed out after %s", timeout)
+	}
+
+	me.result = Result{
+		Name:      me.check.Name,
+		Status:    StatusPass,
+		Critical:  me.check.Critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		CheckedAt: start.UTC(),
+	}
+	if err != nil {
+		me.result.Status = StatusFail
+		me.result.Error = err.Error()
+	}
+
+	return me.result
+}
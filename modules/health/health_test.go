@@ -0,0 +1,200 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRegistry - returns a registry as registered in the container
+func newRegistry() *Registry {
+	return GetRegistry()[0].Value.(*Registry)
+}
+
+// pass - a check that succeeds
+func pass(context.Context) error { return nil }
+
+// fail - a check that reports a problem
+func fail(context.Context) error { return errors.New("down") }
+
+func TestReadyStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+		status string
+	}{
+		{"no checks", nil, StatusPass},
+		{"all passing", []Check{{Name: "a", Critical: true, Func: pass}, {Name: "b", Func: pass}}, StatusPass},
+		{"non critical failure", []Check{{Name: "a", Critical: true, Func: pass}, {Name: "b", Func: fail}}, StatusDegraded},
+		{"critical failure", []Check{{Name: "a", Critical: true, Func: fail}, {Name: "b", Func: fail}}, StatusFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRegistry()
+			for _, c := range tt.checks {
+				r.Register(c)
+			}
+
+			report := r.Ready(context.Background())
+			if report.Status != tt.status {
+				t.Errorf("expected %s, got %s", tt.status, report.Status)
+			}
+			if report.Healthy() != (tt.status != StatusFail) {
+				t.Errorf("healthy %v with status %s", report.Healthy(), report.Status)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Fatalf("expected %d results, got %d", len(tt.checks), len(report.Checks))
+			}
+			for i, c := range tt.checks {
+				if report.Checks[i].Name != c.Name || report.Checks[i].Critical != c.Critical {
+					t.Errorf("result %d: %+v", i, report.Checks[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLiveRunsLivenessChecksOnly(t *testing.T) {
+	r := newRegistry().
+		Register(Check{Name: "process", Critical: true, Liveness: true, Func: pass}).
+		Register(Check{Name: "database", Critical: true, Func: fail})
+
+	live := r.Live(context.Background())
+	if live.Status != StatusPass || len(live.Checks) != 1 || live.Checks[0].Name != "process" {
+		t.Errorf("unexpected liveness report %+v", live)
+	}
+
+	if ready := r.Ready(context.Background()); ready.Status != StatusFail || len(ready.Checks) != 2 {
+		t.Errorf("unexpected readiness report %+v", ready)
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   Check
+		problem string
+	}{
+		{"error", Check{Func: fail}, "down"},
+		{"panic", Check{Func: func(context.Context) error { panic("boom") }}, "check panicked: boom"},
+		// the check error and the timeout race, either one fails the check
+		{"check timeout", Check{Timeout: 20 * time.Millisecond, Func: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}}, ""},
+		{"ignored timeout", Check{Timeout: 20 * time.Millisecond, Func: func(context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		}}, "check timed out after 20ms"},
+		{"registry timeout", Check{Func: func(context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		}}, "check timed out after 30ms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRegistry().Configure(30*time.Millisecond, 0)
+			tt.check.Name = tt.name
+			tt.check.Critical = true
+			r.Register(tt.check)
+
+			start := time.Now()
+			report := r.Ready(context.Background())
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("probe returned after %s", elapsed)
+			}
+
+			result := report.Checks[0]
+			if report.Status != StatusFail || result.Status != StatusFail {
+				t.Fatalf("expected a failure, got %+v", report)
+			}
+			if !strings.Contains(result.Error, tt.problem) || result.Error == "" {
+				t.Errorf("expected `%s`, got `%s`", tt.problem, result.Error)
+			}
+		})
+	}
+}
+
+func TestResultsAreCached(t *testing.T) {
+	var runs int32
+	check := Check{Name: "counted", Func: func(context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}}
+
+	r := newRegistry().Configure(0, 50*time.Millisecond).Register(check)
+	r.Ready(context.Background())
+	r.Ready(context.Background())
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Fatalf("expected 1 run within the cache ttl, got %d", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	r.Ready(context.Background())
+	if n := atomic.LoadInt32(&runs); n != 2 {
+		t.Fatalf("expected 2 runs after the cache ttl, got %d", n)
+	}
+
+	// registering the check again drops its cached result
+	r.Register(check)
+	r.Ready(context.Background())
+	if n := atomic.LoadInt32(&runs); n != 3 {
+		t.Fatalf("expected 3 runs after the check was replaced, got %d", n)
+	}
+}
+
+func TestConcurrentProbesShareARun(t *testing.T) {
+	var runs int32
+	release := make(chan struct{})
+	r := newRegistry().Register(Check{Name: "slow", Func: func(context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		<-release
+		return nil
+	}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if report := r.Ready(context.Background()); report.Status != StatusPass {
+				t.Errorf("unexpected report %+v", report)
+			}
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("expected 1 run for concurrent probes, got %d", n)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	r := newRegistry().Register(Check{Name: "process", Liveness: true, Func: pass})
+	if r.ShuttingDown() {
+		t.Fatal("shutting down before Shutdown")
+	}
+
+	r.Shutdown()
+
+	if !r.ShuttingDown() {
+		t.Fatal("not shutting down after Shutdown")
+	}
+	ready := r.Ready(context.Background())
+	if ready.Status != StatusFail || ready.Checks[len(ready.Checks)-1].Name != "shutdown" {
+		t.Errorf("readiness must fail while shutting down, got %+v", ready)
+	}
+	// the process is still alive while it drains
+	if live := r.Live(context.Background()); live.Status != StatusPass {
+		t.Errorf("liveness must pass while shutting down, got %+v", live)
+	}
+}
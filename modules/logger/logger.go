@@ -1,13 +1,16 @@
 package logs
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"io"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -30,6 +33,9 @@ const (
 
 var (
 	osExit = os.Exit
+
+	// pending tracks log files that are still being written in the background
+	pending sync.WaitGroup
 )
 
 // Log represents information about a rest server log.
@@ -186,7 +192,9 @@ func (l *Log) Dump() {
 		fmt.Print(line)
 	}
 
+	pending.Add(1)
 	go func() {
+		defer pending.Done()
 
 		if l.identifier != "" {
 			filename = fmt.Sprintf("%s/%d.%s.log", l.folder, time.Now().UnixNano(), l.identifier)
@@ -201,6 +209,44 @@ func (l *Log) Dump() {
 	}()
 }
 
+// Wait blocks until all the pending log files are written or the context is done.
+func Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Cleanup deletes the log files in folder last modified before the retention period
+func Cleanup(folder string, retention time.Duration) (removed int, err error) {
+	files, err := filepath.Glob(filepath.Join(folder, "*.log"))
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-retention)
+	for _, f := range files {
+		info, sErr := os.Stat(f)
+		if sErr != nil || info.IsDir() || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err = os.Remove(f); err != nil {
+			return
+		}
+		removed++
+	}
+
+	return
+}
+
 // Exist checks if folder or file exist
 func Exist(path string) (bool, error) {
 	_, err := os.Stat(path)
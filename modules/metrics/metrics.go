@@ -0,0 +1,328 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"httpframwork/modules/container"
+)
+
+type (
+	// Registry holds the metric families exposed in the Prometheus text format
+	Registry struct {
+		sync.RWMutex
+		families map[string]*family
+	}
+
+	// family is a metric name with one series per combination of label values
+	family struct {
+		sync.RWMutex
+		name    string
+		help    string
+		kind    string
+		labels  []string
+		buckets []float64
+		series  map[string]*series
+		fn      func() float64
+	}
+
+	// series holds the value of one label combination
+	series struct {
+		labels []string
+		// value is a float64 stored as bits, it is the sum of a histogram
+		value   uint64
+		count   uint64
+		buckets []uint64
+	}
+
+	// Counter is a family of values that only go up
+	Counter struct{ f *family }
+
+	// Gauge is a family of values that go up and down
+	Gauge struct{ f *family }
+
+	// Histogram is a family of observations counted in buckets
+	Histogram struct{ f *family }
+
+	// Value is one series of a counter or gauge
+	Value struct{ s *series }
+
+	// Observer is one series of a histogram
+	Observer struct {
+		s       *series
+		buckets []float64
+	}
+)
+
+const (
+	InstanceKey = "Metrics"
+
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+)
+
+var (
+	// DefBuckets suits request latencies in seconds
+	DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	// SizeBuckets suits payload sizes in bytes
+	SizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+)
+
+// GetRegistry function ...
+func GetRegistry() container.Registries {
+	obj := &Registry{
+		families: make(map[string]*family),
+	}
+
+	return container.Registries{
+		container.Registry{
+			Key:   InstanceKey,
+			Value: obj,
+		},
+	}
+}
+
+// GetInstance function ...
+func GetInstance(c *container.Container) *Registry {
+	return c.Get(InstanceKey).(*Registry)
+}
+
+// NewCounter method - registers a counter, registering the same name again returns the existing one
+func (me *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{me.register(name, help, kindCounter, labels, nil, nil)}
+}
+
+// NewGauge method - registers a gauge, registering the same name again returns the existing one
+func (me *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{me.register(name, help, kindGauge, labels, nil, nil)}
+}
+
+// NewGaugeFunc method - registers a gauge without labels reading its value from fn on every scrape
+func (me *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	me.register(name, help, kindGauge, nil, nil, fn)
+}
+
+// NewCounterFunc method - registers a counter without labels reading its value from fn on every scrape
+func (me *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	me.register(name, help, kindCounter, nil, nil, fn)
+}
+
+// NewHistogram method - registers a histogram with the upper bounds of its buckets in increasing order
+func (me *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{me.register(name, help, kindHistogram, labels, buckets, nil)}
+}
+
+// register - adds the family or returns the one registered before with the same definition.
+// Registering a name with another kind or labels is a programming error and panics.
+func (me *Registry) register(name, help, kind string, labels []string, buckets []float64, fn func() float64) *family {
+	me.Lock()
+	defer me.Unlock()
+
+	if f, ok := me.families[name]; ok {
+		if f.kind != kind || strings.Join(f.labels, ",") != strings.Join(labels, ",") {
+			panic(fmt.Sprintf("metric %s registered twice with different kinds or labels", name))
+		}
+		return f
+	}
+
+	f := &family{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+		fn:      fn,
+	}
+	me.families[name] = f
+
+	return f
+}
+
+// With method - returns the series of the label values, given in the registration order
+func (me *Counter) With(values ...string) *Value {
+	return &Value{me.f.get(values)}
+}
+
+// With method - returns the series of the label values, given in the registration order
+func (me *Gauge) With(values ...string) *Value {
+	return &Value{me.f.get(values)}
+}
+
+// With method - returns the series of the label values, given in the registration order
+func (me *Histogram) With(values ...string) *Observer {
+	return &Observer{s: me.f.get(values), buckets: me.f.buckets}
+}
+
+// get - returns the series of the label values, creating it on first use
+func (me *family) get(values []string) *series {
+	if len(values) != len(me.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", me.name, len(me.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+
+	me.RLock()
+	s, ok := me.series[key]
+	me.RUnlock()
+	if ok {
+		return s
+	}
+
+	me.Lock()
+	defer me.Unlock()
+	if s, ok = me.series[key]; !ok {
+		s = &series{labels: append([]string(nil), values...)}
+		if me.kind == kindHistogram {
+			s.buckets = make([]uint64, len(me.buckets))
+		}
+		me.series[key] = s
+	}
+
+	return s
+}
+
+// Inc method - adds one
+func (me *Value) Inc() { me.Add(1) }
+
+// Dec method - subtracts one
+func (me *Value) Dec() { me.Add(-1) }
+
+// Add method - adds delta, counters must only be given positive deltas
+func (me *Value) Add(delta float64) {
+	for {
+		old := atomic.LoadUint64(&me.s.value)
+		next := math.Float64bits(math.Float64frombits(old) + delta)
+		if atomic.CompareAndSwapUint64(&me.s.value, old, next) {
+			return
+		}
+	}
+}
+
+// Set method - replaces the value of a gauge
+func (me *Value) Set(v float64) {
+	atomic.StoreUint64(&me.s.value, math.Float64bits(v))
+}
+
+// Observe method - counts v in every bucket it fits in
+func (me *Observer) Observe(v float64) {
+	for i, upper := range me.buckets {
+		if v <= upper {
+			atomic.AddUint64(&me.s.buckets[i], 1)
+		}
+	}
+	atomic.AddUint64(&me.s.count, 1)
+	(&Value{me.s}).Add(v)
+}
+
+// Handler method - serves the metrics in the Prometheus text exposition format
+func (me *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		me.Write(w)
+	})
+}
+
+// Write method - writes every family sorted by name
+func (me *Registry) Write(w io.Writer) error {
+	me.RLock()
+	families := make([]*family, 0, len(me.families))
+	for _, f := range me.families {
+		families = append(families, f)
+	}
+	me.RUnlock()
+
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	b := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(b)
+	}
+
+	return b.Flush()
+}
+
+// write - writes the family header followed by its series sorted by label values
+func (me *family) write(b *bufio.Writer) {
+	fmt.Fprintf(b, "# HELP %s %s\n", me.name, escapeHelp(me.help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", me.name, me.kind)
+
+	if me.fn != nil {
+		fmt.Fprintf(b, "%s %s\n", me.name, formatFloat(me.fn()))
+		return
+	}
+
+	me.RLock()
+	keys := make([]string, 0, len(me.series))
+	for k := range me.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	all := make([]*series, len(keys))
+	for i, k := range keys {
+		all[i] = me.series[k]
+	}
+	me.RUnlock()
+
+	for _, s := range all {
+		value := formatFloat(math.Float64frombits(atomic.LoadUint64(&s.value)))
+		if me.kind != kindHistogram {
+			fmt.Fprintf(b, "%s%s %s\n", me.name, me.labelSet(s.labels, "", ""), value)
+			continue
+		}
+
+		for i, upper := range me.buckets {
+			fmt.Fprintf(b, "%s_bucket%s %d\n", me.name, me.labelSet(s.labels, "le", formatFloat(upper)), atomic.LoadUint64(&s.buckets[i]))
+		}
+		count := atomic.LoadUint64(&s.count)
+		fmt.Fprintf(b, "%s_bucket%s %d\n", me.name, me.labelSet(s.labels, "le", "+Inf"), count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", me.name, me.labelSet(s.labels, "", ""), value)
+		fmt.Fprintf(b, "%s_count%s %d\n", me.name, me.labelSet(s.labels, "", ""), count)
+	}
+}
+
+// labelSet - formats the labels of a series with an optional extra label
+func (me *family) labelSet(values []string, extraName, extraValue string) string {
+	if len(values) == 0 && extraName == "" {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(values)+1)
+	for i, v := range values {
+		pairs = append(pairs, me.labels[i]+`="`+escapeLabel(v)+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+extraValue+`"`)
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
@@ -0,0 +1,192 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newRegistry - returns an empty registry
+func newRegistry() *Registry {
+	return GetRegistry()[0].Value.(*Registry)
+}
+
+// output - returns the exposition of the registry
+func output(t *testing.T, r *Registry) string {
+	t.Helper()
+
+	var b strings.Builder
+	if err := r.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	return b.String()
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		record func(r *Registry)
+		want   string
+	}{
+		{
+			"counter",
+			func(r *Registry) {
+				c := r.NewCounter("http_requests_total", "Requests served.", "method", "code")
+				c.With("GET", "200").Inc()
+				c.With("GET", "200").Add(2)
+				c.With("POST", "500").Inc()
+			},
+			`# HELP http_requests_total Requests served.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",code="200"} 3
+http_requests_total{method="POST",code="500"} 1
+`,
+		},
+		{
+			"gauge",
+			func(r *Registry) {
+				g := r.NewGauge("queue_size", "Waiting jobs.")
+				g.With().Set(5)
+				g.With().Dec()
+				g.With().Add(0.5)
+			},
+			`# HELP queue_size Waiting jobs.
+# TYPE queue_size gauge
+queue_size 4.5
+`,
+		},
+		{
+			"histogram",
+			func(r *Registry) {
+				h := r.NewHistogram("latency_seconds", "Request latency.", []float64{0.1, 1}, "route")
+				for _, v := range []float64{0.05, 0.1, 0.5, 3} {
+					h.With("/").Observe(v)
+				}
+			},
+			`# HELP latency_seconds Request latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/",le="0.1"} 2
+latency_seconds_bucket{route="/",le="1"} 3
+latency_seconds_bucket{route="/",le="+Inf"} 4
+latency_seconds_sum{route="/"} 3.65
+latency_seconds_count{route="/"} 4
+`,
+		},
+		{
+			"histogram without labels",
+			func(r *Registry) {
+				r.NewHistogram("size_bytes", "Payload size.", []float64{100}).With().Observe(1000)
+			},
+			`# HELP size_bytes Payload size.
+# TYPE size_bytes histogram
+size_bytes_bucket{le="100"} 0
+size_bytes_bucket{le="+Inf"} 1
+size_bytes_sum 1000
+size_bytes_count 1
+`,
+		},
+		{
+			"escaping",
+			func(r *Registry) {
+				r.NewCounter("escaped_total", "Back\\slash and\nnew line.", "path").With("a\"b\\c\nd").Inc()
+			},
+			`# HELP escaped_total Back\\slash and\nnew line.
+# TYPE escaped_total counter
+escaped_total{path="a\"b\\c\nd"} 1
+`,
+		},
+		{
+			"functions",
+			func(r *Registry) {
+				r.NewGaugeFunc("goroutines", "Running goroutines.", func() float64 { return 7 })
+				r.NewCounterFunc("uptime_seconds_total", "Seconds since start.", func() float64 { return 1.5 })
+			},
+			`# HELP goroutines Running goroutines.
+# TYPE goroutines gauge
+goroutines 7
+# HELP uptime_seconds_total Seconds since start.
+# TYPE uptime_seconds_total counter
+uptime_seconds_total 1.5
+`,
+		},
+		{
+			"families sorted by name",
+			func(r *Registry) {
+				r.NewGauge("b", "B.").With().Set(2)
+				r.NewGauge("a", "A.").With().Set(1)
+				r.NewCounter("c", "No series yet.")
+			},
+			`# HELP a A.
+# TYPE a gauge
+a 1
+# HELP b B.
+# TYPE b gauge
+b 2
+# HELP c No series yet.
+# TYPE c counter
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRegistry()
+			tt.record(r)
+			if got := output(t, r); got != tt.want {
+				t.Errorf("expected\n%s\ngot\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	r := newRegistry()
+
+	// the same definition returns the existing family and its series
+	r.NewCounter("jobs_total", "Jobs.", "name").With("a").Inc()
+	r.NewCounter("jobs_total", "Jobs.", "name").With("a").Inc()
+	want := `# HELP jobs_total Jobs.
+# TYPE jobs_total counter
+jobs_total{name="a"} 2
+`
+	if got := output(t, r); got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+
+	tests := []struct {
+		name     string
+		register func()
+	}{
+		{"other kind", func() { r.NewGauge("jobs_total", "Jobs.", "name") }},
+		{"other labels", func() { r.NewCounter("jobs_total", "Jobs.", "job") }},
+		{"no labels", func() { r.NewCounter("jobs_total", "Jobs.") }},
+		{"wrong label values", func() { r.NewCounter("jobs_total", "Jobs.", "name").With("a", "b") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			tt.register()
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := newRegistry()
+	r.NewGauge("up", "Up.").With().Set(1)
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if got := rec.Header().Get("Content-Type"); got != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("unexpected Content-Type %q", got)
+	}
+	if want := "# HELP up Up.\n# TYPE up gauge\nup 1\n"; rec.Body.String() != want {
+		t.Errorf("expected %q, got %q", want, rec.Body.String())
+	}
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// memStats - caches runtime.ReadMemStats for the duration of one scrape
+type memStats struct {
+	sync.Mutex
+	stats runtime.MemStats
+	read  time.Time
+}
+
+func (me *memStats) get() *runtime.MemStats {
+	me.Lock()
+	defer me.Unlock()
+
+	if time.Since(me.read) > time.Second {
+		runtime.ReadMemStats(&me.stats)
+		me.read = time.Now()
+	}
+
+	return &me.stats
+}
+
+// RegisterRuntime function - registers the Go runtime metrics
+func RegisterRuntime(reg *Registry) {
+	ms := &memStats{}
+
+	reg.NewGauge("go_info", "Information about the Go environment.", "version").With(runtime.Version()).Set(1)
+	reg.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+	reg.NewGaugeFunc("go_threads", "Number of OS threads created.", func() float64 {
+		n, _ := runtime.ThreadCreateProfile(nil)
+		return float64(n)
+	})
+	reg.NewGaugeFunc("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", func() float64 {
+		return float64(ms.get().Alloc)
+	})
+	reg.NewCounterFunc("go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.", func() float64 {
+		return float64(ms.get().TotalAlloc)
+	})
+	reg.NewGaugeFunc("go_memstats_sys_bytes", "Number of bytes obtained from system.", func() float64 {
+		return float64(ms.get().Sys)
+	})
+	reg.NewGaugeFunc("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", func() float64 {
+		return float64(ms.get().HeapInuse)
+	})
+	reg.NewGaugeFunc("go_memstats_heap_objects", "Number of allocated objects.", func() float64 {
+		return float64(ms.get().HeapObjects)
+	})
+	reg.NewCounterFunc("go_gc_cycles_total", "Number of completed GC cycles.", func() float64 {
+		return float64(ms.get().NumGC)
+	})
+	reg.NewCounterFunc("go_gc_pause_seconds_total", "Total time spent in GC stop-the-world pauses.", func() float64 {
+		return float64(ms.get().PauseTotalNs) / float64(time.Second)
+	})
+}
@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"httpframwork/modules/container"
+)
+
+type (
+	// Limiter allows a steady rate of requests per client with bursts, using one token bucket per key
+	Limiter struct {
+		sync.Mutex
+		rate    float64
+		burst   float64
+		buckets map[string]*bucket
+		swept   time.Time
+		now     func() time.Time
+	}
+
+	bucket struct {
+		tokens float64
+		last   time.Time
+	}
+)
+
+const (
+	InstanceKey = "RateLimit"
+
+	// sweepInterval - how often buckets that refilled completely are dropped
+	sweepInterval = time.Minute
+)
+
+// GetRegistry function ...
+func GetRegistry() container.Registries {
+	obj := &Limiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+
+	return container.Registries{
+		container.Registry{
+			Key:   InstanceKey,
+			Value: obj,
+		},
+	}
+}
+
+// GetInstance function ...
+func GetInstance(c *container.Container) *Limiter {
+	return c.Get(InstanceKey).(*Limiter)
+}
+
+// Configure method - sets the requests per second and the burst of every client, a rate of 0
+// disables the limit. Clients keep their tokens, capped to the new burst.
+func (me *Limiter) Configure(rate float64, burst int) *Limiter {
+	me.Lock()
+	defer me.Unlock()
+
+	me.rate = rate
+	me.burst = math.Max(float64(burst), 1)
+	for _, b := range me.buckets {
+		b.tokens = math.Min(b.tokens, me.burst)
+	}
+
+	return me
+}
+
+// Allow method - takes a token for key, when there is none it returns how long to wait for one
+func (me *Limiter) Allow(key string) (ok bool, retryAfter time.Duration) {
+	me.Lock()
+	defer me.Unlock()
+
+	if me.rate <= 0 {
+		return true, 0
+	}
+
+	now := me.now()
+	me.sweep(now)
+
+	b := me.buckets[key]
+	if b == nil {
+		b = &bucket{tokens: me.burst, last: now}
+		me.buckets[key] = b
+	}
+
+	b.tokens = math.Min(me.burst, b.tokens+now.Sub(b.last).Seconds()*me.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / me.rate * float64(time.Second))
+	}
+	b.tokens--
+
+	return true, 0
+}
+
+// sweep - drops the buckets that are full again, they behave like new ones. The lock must be held.
+func (me *Limiter) sweep(now time.Time) {
+	if now.Sub(me.swept) < sweepInterval {
+		return
+	}
+	me.swept = now
+
+	for key, b := range me.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*me.rate >= me.burst {
+			delete(me.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// newLimiter - returns a limiter on a clock moved by the returned function
+func newLimiter(rate float64, burst int) (*Limiter, func(time.Duration)) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := GetRegistry()[0].Value.(*Limiter)
+	l.now = func() time.Time { return now }
+	l.Configure(rate, burst)
+
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestAllow(t *testing.T) {
+	l, advance := newLimiter(2, 3)
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d within the burst was limited", i+1)
+		}
+	}
+
+	ok, retryAfter := l.Allow("a")
+	if ok || retryAfter != 500*time.Millisecond {
+		t.Fatalf("expected a wait of 500ms, got %v %s", ok, retryAfter)
+	}
+
+	// clients have their own bucket
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("another client was limited")
+	}
+
+	advance(500 * time.Millisecond)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("the refilled token was not given")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("more tokens than the rate allows")
+	}
+
+	// the bucket never holds more than the burst
+	advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		l.Allow("a")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("the bucket grew over the burst")
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	l, _ := newLimiter(0, 1)
+
+	for i := 0; i < 100; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatal("a rate of 0 must not limit")
+		}
+	}
+
+	l.Configure(1, 5)
+	for i := 0; i < 5; i++ {
+		l.Allow("a")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Fatal("expected the new limit to apply")
+	}
+
+	// a lower burst caps the tokens left
+	l.Configure(1, 1)
+	if ok, _ := l.Allow("b"); !ok {
+		t.Fatal("expected one token")
+	}
+	if ok, _ := l.Allow("b"); ok {
+		t.Error("expected the lower burst to apply")
+	}
+
+	l.Configure(0, 1)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("expected the limit to be disabled")
+	}
+}
+
+func TestSweep(t *testing.T) {
+	l, advance := newLimiter(1, 2)
+
+	l.Allow("idle")
+	l.Allow("busy")
+	advance(sweepInterval)
+	l.Allow("busy")
+
+	l.Lock()
+	_, idle := l.buckets["idle"]
+	_, busy := l.buckets["busy"]
+	l.Unlock()
+
+	if idle || !busy {
+		t.Errorf("expected only the refilled bucket to be dropped, idle %v busy %v", idle, busy)
+	}
+}
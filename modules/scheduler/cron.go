@@ -0,0 +1,242 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Schedule computes the next run after a given time
+	Schedule interface {
+		Next(after time.Time) time.Time
+	}
+
+	// cron is a parsed five field expression, every field is a bit set of the allowed values
+	cron struct {
+		minute, hour, dom, month, dow uint64
+		// a restricted day of month and day of week match when either does, as in crontab
+		domStar, dowStar bool
+		// jobs at fixed hours run once when the clocks go back, as in crontab
+		hourStar bool
+		location *time.Location
+	}
+
+	// every runs at a fixed interval from the previous run
+	every struct {
+		interval time.Duration
+	}
+
+	bounds struct {
+		min, max int
+		names    map[string]int
+	}
+)
+
+var (
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	doms    = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is sunday as well, it is folded onto 0 once the field is parsed
+	dows = bounds{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	descriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// LoadLocation returns the time zone with the given name, empty is the local time zone
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+
+	return time.LoadLocation(name)
+}
+
+// Every returns a schedule running at a fixed interval
+func Every(interval time.Duration) Schedule {
+	return every{interval}
+}
+
+// Parse reads a five field cron expression (minute hour day-of-month month day-of-week),
+// a descriptor like @daily or @every 10m. Cron times are evaluated in location.
+func Parse(spec string, location *time.Location) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid interval in `%s`", spec)
+		}
+		return Every(d), nil
+	}
+
+	if expr, ok := descriptors[spec]; ok {
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in `%s`, got %d", spec, len(fields))
+	}
+
+	c := &cron{location: location}
+	var err error
+	for i, f := range []struct {
+		dst *uint64
+		b   bounds
+	}{{&c.minute, minutes}, {&c.hour, hours}, {&c.dom, doms}, {&c.month, months}, {&c.dow, dows}} {
+		if *f.dst, err = parseField(fields[i], f.b); err != nil {
+			return nil, fmt.Errorf("invalid field `%s` in `%s`: %v", fields[i], spec, err)
+		}
+	}
+	c.domStar, c.dowStar = fields[2] == "*", fields[4] == "*"
+	c.hourStar = strings.HasPrefix(fields[1], "*")
+	if c.dow&(1<<7) != 0 {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+
+	return c, nil
+}
+
+// parseField - parses a comma separated list of *, values, ranges and steps
+func parseField(field string, b bounds) (set uint64, err error) {
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step `%s`", part[i+1:])
+			}
+			part = part[:i]
+		}
+
+		lo, hi := b.min, b.max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			r := strings.SplitN(part, "-", 2)
+			if lo, err = b.value(r[0]); err != nil {
+				return
+			}
+			if hi, err = b.value(r[1]); err != nil {
+				return
+			}
+		default:
+			if lo, err = b.value(part); err != nil {
+				return
+			}
+			// a single value with a step runs from the value to the maximum, as in 5/15
+			hi = lo
+			if step > 1 {
+				hi = b.max
+			}
+		}
+
+		if lo > hi {
+			return 0, fmt.Errorf("range %d-%d is reversed", lo, hi)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+
+	return
+}
+
+// value - reads a number or a name within the bounds
+func (b bounds) value(s string) (int, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value `%s`", s)
+	}
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, b.min, b.max)
+	}
+
+	return v, nil
+}
+
+// Next returns the first matching minute after the given time, zero when there is none in five years.
+// Times skipped when the clocks go forward don't run.
+func (c *cron) Next(after time.Time) time.Time {
+	t := after.In(c.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	floor := wallClock(after.In(c.location))
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = forward(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.location))
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.location))
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.location))
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		if !c.hourStar && !wallClock(t).After(floor) {
+			// the hour is repeated after the clocks went back
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// forward - returns next, a time built with time.Date, after t. A time skipped when the clocks
+// go forward is normalized to the hour before the change, which can be t itself.
+func forward(t, next time.Time) time.Time {
+	for !next.After(t) {
+		next = next.Add(time.Hour)
+	}
+
+	return next
+}
+
+// wallClock - returns the date and time read on a clock in the location of t, to the minute
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// Next returns the time one interval after the given time
+func (e every) Next(after time.Time) time.Time {
+	return after.Add(e.interval)
+}
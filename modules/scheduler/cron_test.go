@@ -0,0 +1,174 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		spec    string
+		problem string
+	}{
+		{"* * * *", "expected 5 fields in `* * * *`, got 4"},
+		{"* * * * * *", "expected 5 fields"},
+		{"60 * * * *", "value 60 out of range 0-59"},
+		{"* 24 * * *", "value 24 out of range 0-23"},
+		{"* * 0 * *", "value 0 out of range 1-31"},
+		{"* * * 13 *", "value 13 out of range 1-12"},
+		{"* * * * 8", "value 8 out of range 0-7"},
+		{"5-1 * * * *", "range 5-1 is reversed"},
+		{"* * * * sat-mon", "range 6-1 is reversed"},
+		{"*/0 * * * *", "invalid step `0`"},
+		{"*/x * * * *", "invalid step `x`"},
+		{"x * * * *", "invalid value `x`"},
+		{"* * * smarch *", "invalid value `smarch`"},
+		{"@every nope", "invalid interval in `@every nope`"},
+		{"@every -1m", "invalid interval"},
+		{"@fortnightly", "expected 5 fields"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			if _, err := Parse(tt.spec, time.UTC); err == nil || !strings.Contains(err.Error(), tt.problem) {
+				t.Errorf("expected `%s`, got %v", tt.problem, err)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	// a thursday
+	after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec string
+		want []time.Time
+	}{
+		{"* * * * *", []time.Time{at(1, 1, 0, 1), at(1, 1, 0, 2)}},
+		{"*/15 * * * *", []time.Time{at(1, 1, 0, 15), at(1, 1, 0, 30), at(1, 1, 0, 45), at(1, 1, 1, 0)}},
+		{"5/20 * * * *", []time.Time{at(1, 1, 0, 5), at(1, 1, 0, 25), at(1, 1, 0, 45), at(1, 1, 1, 5)}},
+		{"0,30 9-17/4 * * *", []time.Time{at(1, 1, 9, 0), at(1, 1, 9, 30), at(1, 1, 13, 0), at(1, 1, 13, 30), at(1, 1, 17, 0)}},
+		{"30 8 * * mon-fri", []time.Time{at(1, 1, 8, 30), at(1, 2, 8, 30), at(1, 5, 8, 30)}},
+		{"0 0 * * SAT,Sun", []time.Time{at(1, 3, 0, 0), at(1, 4, 0, 0), at(1, 10, 0, 0)}},
+		// 7 is sunday, alone or as the end of a range
+		{"0 0 * * 7", []time.Time{at(1, 4, 0, 0), at(1, 11, 0, 0)}},
+		{"0 0 * * 5-7", []time.Time{at(1, 2, 0, 0), at(1, 3, 0, 0), at(1, 4, 0, 0), at(1, 9, 0, 0)}},
+		{"0 0 * * 1-7", []time.Time{at(1, 2, 0, 0), at(1, 3, 0, 0), at(1, 4, 0, 0), at(1, 5, 0, 0)}},
+		// a restricted day of month or day of week matches
+		{"0 0 13 * fri", []time.Time{at(1, 2, 0, 0), at(1, 9, 0, 0), at(1, 13, 0, 0), at(1, 16, 0, 0)}},
+		{"0 0 13 * *", []time.Time{at(1, 13, 0, 0), at(2, 13, 0, 0)}},
+		{"0 0 * * fri", []time.Time{at(1, 2, 0, 0), at(1, 9, 0, 0)}},
+		{"0 0 31 * *", []time.Time{at(1, 31, 0, 0), at(3, 31, 0, 0), at(5, 31, 0, 0)}},
+		{"0 12 1 jan,JUL *", []time.Time{at(1, 1, 12, 0), at(7, 1, 12, 0)}},
+		{"0 0 1 */3 *", []time.Time{at(4, 1, 0, 0), at(7, 1, 0, 0), at(10, 1, 0, 0)}},
+		{"0 0 29 2 *", []time.Time{time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)}},
+		{"@hourly", []time.Time{at(1, 1, 1, 0), at(1, 1, 2, 0)}},
+		{"@daily", []time.Time{at(1, 2, 0, 0)}},
+		{"@weekly", []time.Time{at(1, 4, 0, 0), at(1, 11, 0, 0)}},
+		{"@monthly", []time.Time{at(2, 1, 0, 0)}},
+		{"@yearly", []time.Time{time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		{"@every 90s", []time.Time{at(1, 1, 0, 1).Add(30 * time.Second), at(1, 1, 0, 3)}},
+		// there is no february 30th, the search stops after five years
+		{"0 0 30 2 *", []time.Time{{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := Parse(tt.spec, time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			next := after
+			for _, want := range tt.want {
+				if next = s.Next(next); !next.Equal(want) {
+					t.Fatalf("expected %s, got %s", want, next)
+				}
+			}
+		})
+	}
+}
+
+func TestNextSeconds(t *testing.T) {
+	s, _ := Parse("* * * * *", time.UTC)
+
+	// the next run is the next whole minute
+	after := time.Date(2026, 1, 1, 10, 0, 59, 999, time.UTC)
+	if got, want := s.Next(after), time.Date(2026, 1, 1, 10, 1, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestNextLocation(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := Parse("0 9 * * *", tokyo)
+
+	// 9:00 in Tokyo is midnight UTC
+	got := s.Next(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	if want := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC); !got.Equal(want) || got.Location() != tokyo {
+		t.Errorf("expected %s in Tokyo, got %s", want, got)
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, ny)
+	}
+	// the clocks go back from 2:00 EDT to 1:00 EST on november 1st
+	secondOneThirty := at(11, 1, 1, 30).Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		spec  string
+		after time.Time
+		want  []time.Time
+	}{
+		// 2:00 to 2:59 don't exist on march 8th
+		{"skipped by spring forward", "30 2 * * *", at(3, 8, 0, 0), []time.Time{at(3, 9, 2, 30)}},
+		{"around spring forward", "0 * * * *", at(3, 8, 0, 30), []time.Time{at(3, 8, 1, 0), at(3, 8, 3, 0), at(3, 8, 4, 0)}},
+		{"fixed time in the repeated hour", "30 1 * * *", at(11, 1, 0, 0), []time.Time{at(11, 1, 1, 30), at(11, 2, 1, 30)}},
+		{"hourly through the repeated hour", "30 * * * *", at(11, 1, 0, 0), []time.Time{at(11, 1, 0, 30), at(11, 1, 1, 30), secondOneThirty, at(11, 1, 2, 30)}},
+		{"daily across the change", "0 12 * * *", at(10, 31, 13, 0), []time.Time{at(11, 1, 12, 0), at(11, 2, 12, 0)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec, ny)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			next := tt.after
+			for _, want := range tt.want {
+				if next = s.Next(next); !next.Equal(want) {
+					t.Fatalf("expected %s, got %s", want, next)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	if loc, err := LoadLocation(""); err != nil || loc != time.Local {
+		t.Errorf("expected the local time zone for an empty name, got %v %v", loc, err)
+	}
+	if loc, err := LoadLocation("UTC"); err != nil || loc != time.UTC {
+		t.Errorf("expected UTC, got %v %v", loc, err)
+	}
+	if _, err := LoadLocation("Mars/Olympus"); err == nil {
+		t.Error("expected an unknown time zone to fail")
+	}
+}
@@ -0,0 +1,415 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"httpframwork/modules/constant"
+	"httpframwork/modules/container"
+	"httpframwork/modules/logger"
+)
+
+type (
+	// Overlap decides what happens when a run is due while the previous one is still running
+	Overlap int
+
+	// JobFunc is the work of a job, entries added to l are written with the run log
+	JobFunc func(ctx context.Context, l *logs.Log) error
+
+	// Job is a named task run on a schedule
+	Job struct {
+		Name string
+		// Spec is a cron expression, a descriptor like @daily or @every 10m, ignored when Interval is set
+		Spec     string
+		Interval time.Duration
+		// Jitter delays every scheduled run by a random duration up to its value
+		Jitter  time.Duration
+		Overlap Overlap
+		// Timeout cancels the context of a run, zero lets it run until the scheduler stops
+		Timeout time.Duration
+		Func    JobFunc
+	}
+
+	// JobStatus describes a job and its last run
+	JobStatus struct {
+		Name         string    `json:"name"`
+		Schedule     string    `json:"schedule"`
+		Overlap      string    `json:"overlap"`
+		Next         time.Time `json:"next,omitzero"`
+		Running      bool      `json:"running"`
+		Runs         int       `json:"runs"`
+		Failures     int       `json:"failures"`
+		Skipped      int       `json:"skipped"`
+		LastStart    time.Time `json:"last_start,omitzero"`
+		LastDuration string    `json:"last_duration,omitempty"`
+		LastError    string    `json:"last_error,omitempty"`
+	}
+
+	// job is a registered job with its state
+	job struct {
+		sync.Mutex
+		Job
+		pending chan struct{}
+		status  JobStatus
+	}
+
+	// Scheduler runs the registered jobs while the application runs
+	Scheduler struct {
+		sync.Mutex
+		jobs     []*job
+		location *time.Location
+		logDir   string
+		ctx      context.Context
+		cancel   context.CancelFunc
+		wg       sync.WaitGroup
+	}
+)
+
+const InstanceKey = "Scheduler"
+
+const (
+	// OverlapSkip drops a run that is due while the job is running
+	OverlapSkip Overlap = iota
+	// OverlapQueue runs it once the current run is done, at most one run waits
+	OverlapQueue
+)
+
+var (
+	// ErrUnknownJob is returned when triggering a job that is not registered
+	ErrUnknownJob = errors.New("unknown job")
+	// ErrSkipped is returned when a triggered run is dropped by the overlap policy
+	ErrSkipped = errors.New("job is already running")
+	// ErrNotRunning is returned when triggering a job while the scheduler is stopped
+	ErrNotRunning = errors.New("scheduler is not running")
+)
+
+// GetRegistry function ...
+func GetRegistry() container.Registries {
+	obj := &Scheduler{
+		location: time.Local,
+	}
+
+	return container.Registries{
+		container.Registry{
+			Key:   InstanceKey,
+			Value: obj,
+		},
+	}
+}
+
+// GetInstance function ...
+func GetInstance(c *container.Container) *Scheduler {
+	return c.Get(InstanceKey).(*Scheduler)
+}
+
+// Configure method - sets the time zone of cron expressions and the folder of the run logs.
+// Cron expressions are evaluated in the zone set when the scheduler starts, jobs registered before apply it too.
+func (me *Scheduler) Configure(location *time.Location, logDir string) *Scheduler {
+	me.Lock()
+	defer me.Unlock()
+
+	me.location = location
+	me.logDir = logDir
+
+	return me
+}
+
+// Register method - adds a job, a job registered while the scheduler runs is scheduled at once
+func (me *Scheduler) Register(j Job) error {
+	if j.Name == "" || j.Func == nil {
+		return errors.New("job needs a name and a function")
+	}
+
+	me.Lock()
+	defer me.Unlock()
+
+	for _, existing := range me.jobs {
+		if existing.Name == j.Name {
+			return fmt.Errorf("job %s registered twice", j.Name)
+		}
+	}
+
+	if _, err := j.parse(me.location); err != nil {
+		return fmt.Errorf("job %s: %v", j.Name, err)
+	}
+
+	desc := j.Spec
+	if j.Interval > 0 {
+		desc = "@every " + j.Interval.String()
+	}
+
+	overlap := "skip"
+	if j.Overlap == OverlapQueue {
+		overlap = "queue"
+	}
+
+	added := &job{
+		Job:     j,
+		pending: make(chan struct{}, 1),
+		status:  JobStatus{Name: j.Name, Schedule: desc, Overlap: overlap},
+	}
+	me.jobs = append(me.jobs, added)
+
+	if me.cancel != nil {
+		me.launch(added)
+	}
+
+	return nil
+}
+
+// Start method - schedules every job until Stop is called or ctx is done
+func (me *Scheduler) Start(ctx context.Context) {
+	me.Lock()
+	defer me.Unlock()
+
+	if me.cancel != nil {
+		return
+	}
+
+	me.ctx, me.cancel = context.WithCancel(ctx)
+	for _, j := range me.jobs {
+		me.launch(j)
+	}
+}
+
+// Stop method - stops scheduling and waits for the running jobs until ctx is done
+func (me *Scheduler) Stop(ctx context.Context) error {
+	me.Lock()
+	cancel := me.cancel
+	me.ctx, me.cancel = nil, nil
+	me.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		me.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("jobs still running: %v", ctx.Err())
+	}
+}
+
+// Trigger method - runs the job now, following its overlap policy
+func (me *Scheduler) Trigger(name string) error {
+	j := me.find(name)
+	if j == nil {
+		return ErrUnknownJob
+	}
+
+	me.Lock()
+	running := me.cancel != nil
+	me.Unlock()
+	if !running {
+		return ErrNotRunning
+	}
+
+	if !j.enqueue() {
+		return ErrSkipped
+	}
+
+	return nil
+}
+
+// List method - returns the status of every job sorted by name
+func (me *Scheduler) List() []JobStatus {
+	me.Lock()
+	jobs := append([]*job(nil), me.jobs...)
+	me.Unlock()
+
+	list := make([]JobStatus, 0, len(jobs))
+	for _, j := range jobs {
+		j.Lock()
+		list = append(list, j.status)
+		j.Unlock()
+	}
+	sort.Slice(list, func(i, k int) bool { return list[i].Name < list[k].Name })
+
+	return list
+}
+
+func (me *Scheduler) find(name string) *job {
+	me.Lock()
+	defer me.Unlock()
+
+	for _, j := range me.jobs {
+		if j.Name == name {
+			return j
+		}
+	}
+
+	return nil
+}
+
+// launch - starts the goroutines of a job with its schedule in the current time zone, the lock must be held
+func (me *Scheduler) launch(j *job) {
+	// the spec was checked by Register, it parses in any time zone
+	schedule, _ := j.parse(me.location)
+
+	me.wg.Add(2)
+	go me.tick(me.ctx, j, schedule)
+	go me.work(me.ctx, j)
+}
+
+// parse - returns the schedule of the job, cron times are evaluated in location
+func (j Job) parse(location *time.Location) (Schedule, error) {
+	if j.Interval > 0 {
+		return Every(j.Interval), nil
+	}
+
+	return Parse(j.Spec, location)
+}
+
+// tick - waits for the next scheduled time of the job and queues a run
+func (me *Scheduler) tick(ctx context.Context, j *job, schedule Schedule) {
+	defer me.wg.Done()
+
+	var next time.Time
+	for {
+		if next = nextRun(schedule, next, time.Now()); next.IsZero() {
+			log.Printf("job %s has no next run, it is not scheduled anymore\n", j.Name)
+			return
+		}
+
+		fire := next
+		if j.Jitter > 0 {
+			fire = fire.Add(time.Duration(rand.Int63n(int64(j.Jitter))))
+		}
+
+		j.Lock()
+		j.status.Next = fire
+		j.Unlock()
+
+		timer := time.NewTimer(time.Until(fire))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		j.enqueue()
+	}
+}
+
+// nextRun - returns the scheduled time following prev, the time of the previous run without its
+// jitter so jittered runs don't drift. The first run and runs after missed ones follow now.
+func nextRun(schedule Schedule, prev, now time.Time) time.Time {
+	if prev.IsZero() {
+		return schedule.Next(now)
+	}
+
+	next := schedule.Next(prev)
+	if next.Before(now) {
+		// the scheduled times were missed, e.g. while the host was suspended
+		return schedule.Next(now)
+	}
+
+	return next
+}
+
+// enqueue - queues a run unless the overlap policy drops it
+func (j *job) enqueue() bool {
+	j.Lock()
+	skip := j.Overlap == OverlapSkip && j.status.Running
+	j.Unlock()
+
+	if !skip {
+		select {
+		case j.pending <- struct{}{}:
+			return true
+		default:
+			// a run is already waiting
+		}
+	}
+
+	j.Lock()
+	j.status.Skipped++
+	j.Unlock()
+
+	return false
+}
+
+// work - runs the queued runs of the job one at a time
+func (me *Scheduler) work(ctx context.Context, j *job) {
+	defer me.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-j.pending:
+			me.run(ctx, j)
+		}
+	}
+}
+
+// run - runs the job once, recovering from panics, and writes the run log
+func (me *Scheduler) run(ctx context.Context, j *job) {
+	me.Lock()
+	logDir := me.logDir
+	me.Unlock()
+
+	l := logs.New(logDir)
+	start := time.Now()
+
+	j.Lock()
+	j.status.Running = true
+	j.status.LastStart = start
+	j.Unlock()
+
+	l.Print("Start ", start.UTC().Format(constant.DefaultDateTimeFormat))
+	l.Print("Job ", j.Name)
+
+	if j.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.Timeout)
+		defer cancel()
+	}
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+				l.Print("Stack trace ", string(debug.Stack()))
+			}
+		}()
+
+		return j.Func(ctx, l)
+	}()
+
+	duration := time.Since(start)
+
+	j.Lock()
+	j.status.Running = false
+	j.status.Runs++
+	j.status.LastDuration = duration.String()
+	j.status.LastError = ""
+	if err != nil {
+		j.status.Failures++
+		j.status.LastError = err.Error()
+	}
+	j.Unlock()
+
+	if err != nil {
+		l.Print("Error ", err.Error())
+	}
+	l.Print("Duration ", duration.String())
+	l.Print("End ", time.Now().UTC().Format(constant.DefaultDateTimeFormat))
+	l.Dump()
+}
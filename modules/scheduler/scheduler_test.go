@@ -0,0 +1,319 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"httpframwork/modules/logger"
+)
+
+// newScheduler - returns a started scheduler writing its run logs to a temporary folder
+func newScheduler(t *testing.T) *Scheduler {
+	t.Helper()
+
+	s := GetRegistry()[0].Value.(*Scheduler).Configure(time.UTC, t.TempDir())
+	s.Start(context.Background())
+	t.Cleanup(func() {
+		s.Stop(context.Background())
+		logs.Wait(context.Background())
+	})
+
+	return s
+}
+
+// status - returns the status of the job
+func status(t *testing.T, s *Scheduler, name string) JobStatus {
+	t.Helper()
+
+	for _, st := range s.List() {
+		if st.Name == name {
+			return st
+		}
+	}
+	t.Fatalf("job %s not listed", name)
+
+	return JobStatus{}
+}
+
+// waitFor - waits until the status of the job satisfies cond
+func waitFor(t *testing.T, s *Scheduler, name string, cond func(JobStatus) bool) JobStatus {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st := status(t, s, name)
+		if cond(st) {
+			return st
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected status %+v", st)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// blocking - returns a job function that runs until release is closed, started receives every start
+func blocking(started chan<- struct{}, release <-chan struct{}) JobFunc {
+	return func(ctx context.Context, l *logs.Log) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}
+}
+
+func noop(context.Context, *logs.Log) error { return nil }
+
+func TestRegister(t *testing.T) {
+	s := newScheduler(t)
+
+	tests := []struct {
+		job     Job
+		problem string
+	}{
+		{Job{Spec: "@daily", Func: noop}, "job needs a name and a function"},
+		{Job{Name: "nofunc", Spec: "@daily"}, "job needs a name and a function"},
+		{Job{Name: "invalid", Spec: "61 * * * *", Func: noop}, "job invalid: invalid field `61`"},
+		{Job{Name: "daily", Spec: "@daily", Func: noop}, ""},
+		{Job{Name: "daily", Spec: "@hourly", Func: noop}, "job daily registered twice"},
+		{Job{Name: "interval", Interval: time.Hour, Spec: "ignored", Overlap: OverlapQueue, Func: noop}, ""},
+	}
+	for _, tt := range tests {
+		err := s.Register(tt.job)
+		switch {
+		case tt.problem == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.job.Name, err)
+		case tt.problem != "" && (err == nil || !strings.Contains(err.Error(), tt.problem)):
+			t.Errorf("%s: expected `%s`, got %v", tt.job.Name, tt.problem, err)
+		}
+	}
+
+	list := s.List()
+	if len(list) != 2 || list[0].Name != "daily" || list[1].Name != "interval" {
+		t.Fatalf("unexpected jobs %+v", list)
+	}
+	if list[0].Schedule != "@daily" || list[0].Overlap != "skip" || list[1].Schedule != "@every 1h0m0s" || list[1].Overlap != "queue" {
+		t.Errorf("unexpected statuses %+v", list)
+	}
+	// registered while the scheduler runs, the jobs are scheduled at once
+	waitFor(t, s, "interval", func(st JobStatus) bool { return !st.Next.IsZero() })
+
+	if err := s.Trigger("unknown"); err != ErrUnknownJob {
+		t.Errorf("expected ErrUnknownJob, got %v", err)
+	}
+}
+
+func TestLocation(t *testing.T) {
+	s := GetRegistry()[0].Value.(*Scheduler)
+	defer logs.Wait(context.Background())
+
+	// the job is registered before the time zone is configured, as modules initialized first do
+	if err := s.Register(Job{Name: "nightly", Spec: "0 3 * * *", Func: noop}); err != nil {
+		t.Fatal(err)
+	}
+	zone := time.FixedZone("UTC+05:30", 5*3600+30*60)
+	s.Configure(zone, t.TempDir()).Start(context.Background())
+	defer s.Stop(context.Background())
+
+	next := waitFor(t, s, "nightly", func(st JobStatus) bool { return !st.Next.IsZero() }).Next.In(zone)
+	if next.Hour() != 3 || next.Minute() != 0 {
+		t.Errorf("expected the next run at 03:00 in the configured zone, got %s", next)
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	s := GetRegistry()[0].Value.(*Scheduler).Configure(time.UTC, t.TempDir())
+	defer logs.Wait(context.Background())
+	s.Register(Job{Name: "once", Spec: "@yearly", Func: noop})
+
+	tests := []struct {
+		name  string
+		setup func()
+		job   string
+		want  error
+	}{
+		{"before start", func() {}, "once", ErrNotRunning},
+		{"unknown job", func() {}, "unknown", ErrUnknownJob},
+		{"running", func() { s.Start(context.Background()) }, "once", nil},
+		{"after stop", func() { s.Stop(context.Background()) }, "once", ErrNotRunning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			if err := s.Trigger(tt.job); err != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	base := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	minutely, _ := Parse("* * * * *", time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		prev     time.Time
+		now      time.Time
+		want     time.Time
+	}{
+		{"first interval run", Every(time.Minute), time.Time{}, base.Add(10 * time.Second), base.Add(70 * time.Second)},
+		// the previous run fired 40s late because of its jitter, the next one is not delayed by it
+		{"after a jittered run", Every(time.Minute), base, base.Add(40 * time.Second), base.Add(time.Minute)},
+		{"after missed runs", Every(time.Minute), base, base.Add(5*time.Minute + time.Second), base.Add(6*time.Minute + time.Second)},
+		{"first cron run", minutely, time.Time{}, base.Add(10 * time.Second), base.Add(time.Minute)},
+		{"after a jittered cron run", minutely, base, base.Add(40 * time.Second), base.Add(time.Minute)},
+		{"after missed cron runs", minutely, base, base.Add(5*time.Minute + time.Second), base.Add(6 * time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextRun(tt.schedule, tt.prev, tt.now); !got.Equal(tt.want) {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJitter(t *testing.T) {
+	s := newScheduler(t)
+
+	start := time.Now()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if err := s.Register(Job{Name: name, Interval: time.Hour, Jitter: time.Hour, Func: noop}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jittered := false
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		st := waitFor(t, s, name, func(st JobStatus) bool { return !st.Next.IsZero() })
+		delay := st.Next.Sub(start)
+		if delay < time.Hour || delay >= 2*time.Hour+time.Second {
+			t.Errorf("%s: next run in %s, expected within the interval and the jitter", name, delay)
+		}
+		if delay > time.Hour+time.Second {
+			jittered = true
+		}
+	}
+	if !jittered {
+		t.Error("no run was delayed by its jitter")
+	}
+}
+
+func TestScheduledRuns(t *testing.T) {
+	s := newScheduler(t)
+	if err := s.Register(Job{Name: "fast", Interval: 10 * time.Millisecond, Func: noop}); err != nil {
+		t.Fatal(err)
+	}
+
+	st := waitFor(t, s, "fast", func(st JobStatus) bool { return st.Runs >= 3 })
+	if st.Failures != 0 || st.LastError != "" || st.LastStart.IsZero() || st.LastDuration == "" {
+		t.Errorf("unexpected status %+v", st)
+	}
+}
+
+func TestOverlapSkip(t *testing.T) {
+	s := newScheduler(t)
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	s.Register(Job{Name: "skip", Spec: "@yearly", Overlap: OverlapSkip, Func: blocking(started, release)})
+
+	if err := s.Trigger("skip"); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	waitFor(t, s, "skip", func(st JobStatus) bool { return st.Running })
+
+	if err := s.Trigger("skip"); err != ErrSkipped {
+		t.Errorf("expected the run to be skipped, got %v", err)
+	}
+
+	close(release)
+	st := waitFor(t, s, "skip", func(st JobStatus) bool { return !st.Running && st.Runs == 1 })
+	if st.Skipped != 1 {
+		t.Errorf("expected 1 skipped run, got %+v", st)
+	}
+	if len(started) != 0 {
+		t.Error("the skipped run was started")
+	}
+}
+
+func TestOverlapQueue(t *testing.T) {
+	s := newScheduler(t)
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	s.Register(Job{Name: "queue", Spec: "@yearly", Overlap: OverlapQueue, Func: blocking(started, release)})
+
+	s.Trigger("queue")
+	<-started
+	waitFor(t, s, "queue", func(st JobStatus) bool { return st.Running })
+
+	// one run waits for the current one, the next is dropped
+	if err := s.Trigger("queue"); err != nil {
+		t.Errorf("expected the run to be queued, got %v", err)
+	}
+	if err := s.Trigger("queue"); err != ErrSkipped {
+		t.Errorf("expected the run to be skipped while one is waiting, got %v", err)
+	}
+
+	close(release)
+	<-started
+	st := waitFor(t, s, "queue", func(st JobStatus) bool { return !st.Running && st.Runs == 2 })
+	if st.Skipped != 1 {
+		t.Errorf("expected 1 skipped run, got %+v", st)
+	}
+}
+
+func TestFailures(t *testing.T) {
+	s := newScheduler(t)
+	s.Register(Job{Name: "panic", Spec: "@yearly", Func: func(context.Context, *logs.Log) error {
+		panic("boom")
+	}})
+	s.Register(Job{Name: "error", Spec: "@yearly", Func: func(context.Context, *logs.Log) error {
+		return errors.New("failed")
+	}})
+	s.Register(Job{Name: "timeout", Spec: "@yearly", Timeout: 10 * time.Millisecond, Func: func(ctx context.Context, _ *logs.Log) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	for name, want := range map[string]string{"panic": "panic: boom", "error": "failed", "timeout": "context deadline exceeded"} {
+		s.Trigger(name)
+		st := waitFor(t, s, name, func(st JobStatus) bool { return st.Runs == 1 })
+		if st.Failures != 1 || st.LastError != want || st.Running {
+			t.Errorf("%s: expected `%s`, got %+v", name, want, st)
+		}
+	}
+
+	// a panic does not stop the job, it runs again
+	s.Trigger("panic")
+	waitFor(t, s, "panic", func(st JobStatus) bool { return st.Runs == 2 && st.Failures == 2 })
+}
+
+func TestStop(t *testing.T) {
+	s := GetRegistry()[0].Value.(*Scheduler).Configure(time.UTC, t.TempDir())
+	defer logs.Wait(context.Background())
+
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	s.Register(Job{Name: "stubborn", Spec: "@yearly", Func: blocking(started, release)})
+	s.Start(context.Background())
+
+	s.Trigger("stubborn")
+	<-started
+
+	// a job ignoring its context keeps Stop waiting until the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); err == nil {
+		t.Error("expected Stop to report the running job")
+	}
+
+	close(release)
+	if err := s.Stop(context.Background()); err != nil {
+		t.Errorf("expected a second Stop to do nothing, got %v", err)
+	}
+	waitFor(t, s, "stubborn", func(st JobStatus) bool { return !st.Running && st.Runs == 1 })
+}
@@ -0,0 +1,242 @@
+package static
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Server serves the files of a directory or an embedded filesystem below a path prefix
+	Server struct {
+		files  fs.FS
+		prefix string
+		spa    bool
+		// etags caches the content hash of the served files, keyed by name
+		etags sync.Map
+	}
+
+	// etag is the hash of a file, valid while its size and modification time don't change
+	etag struct {
+		size    int64
+		modTime time.Time
+		value   string
+	}
+
+	// encoding is a precompressed variant looked up next to a file
+	encoding struct {
+		name, ext string
+	}
+)
+
+const (
+	index = "index.html"
+
+	cacheImmutable  = "public, max-age=31536000, immutable"
+	cacheRevalidate = "no-cache"
+)
+
+var (
+	// encodings - precompressed variants in order of preference
+	encodings = []encoding{{"br", ".br"}, {"gzip", ".gz"}}
+
+	// hashed - matches the fingerprint bundlers add to names, as in app.3f9a1c2e.js or index-B7x2kQ9a.css
+	hashed = regexp.MustCompile(`[.-]([0-9A-Za-z_]{8,})\.[0-9A-Za-z]+$`)
+)
+
+// New returns a server for files mounted at prefix, e.g. os.DirFS("./public") or an embed.FS
+// narrowed with fs.Sub. With spa set, GET requests for paths without an extension that match
+// no file get the root index.html, so the client side router can handle them.
+func New(files fs.FS, prefix string, spa bool) *Server {
+	return &Server{
+		files:  files,
+		prefix: strings.TrimSuffix(prefix, "/"),
+		spa:    spa,
+	}
+}
+
+// ServeHTTP - serves the file matching the request path
+func (me *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, ok := me.name(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	err := me.serve(w, r, name)
+	if errors.Is(err, fs.ErrNotExist) && me.spa && path.Ext(name) == "" {
+		err = me.serve(w, r, index)
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, fs.ErrNotExist):
+		http.NotFound(w, r)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// name - maps the request path to a file name, hidden files are never served. The prefix must
+// end on a segment boundary, /assets doesn't match /assetsfoo
+func (me *Server) name(urlPath string) (string, bool) {
+	if urlPath != me.prefix && !strings.HasPrefix(urlPath, me.prefix+"/") {
+		return "", false
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(urlPath, me.prefix)), "/")
+	if name == "" {
+		return index, true
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return "", false
+		}
+	}
+
+	return name, fs.ValidPath(name)
+}
+
+// serve - writes the file, or its index.html when it is a directory, preferring a precompressed variant
+func (me *Server) serve(w http.ResponseWriter, r *http.Request, name string) error {
+	info, err := fs.Stat(me.files, name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		name = path.Join(name, index)
+		if info, err = fs.Stat(me.files, name); err != nil {
+			return err
+		}
+	}
+	if info.IsDir() {
+		return fs.ErrNotExist
+	}
+
+	h := w.Header()
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		h.Set("Content-Type", ctype)
+	}
+	if fingerprinted(path.Base(name)) {
+		h.Set("Cache-Control", cacheImmutable)
+	} else {
+		h.Set("Cache-Control", cacheRevalidate)
+	}
+
+	served, suffix := name, ""
+	for _, e := range encodings {
+		variant, err := fs.Stat(me.files, name+e.ext)
+		if err != nil || variant.IsDir() {
+			continue
+		}
+		h.Set("Vary", "Accept-Encoding")
+		if accepts(r.Header.Get("Accept-Encoding"), e.name) {
+			served, suffix, info = name+e.ext, "-"+e.name, variant
+			h.Set("Content-Encoding", e.name)
+			break
+		}
+	}
+
+	f, err := me.files.Open(served)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(b)
+	}
+
+	tag, err := me.etag(served, info, content)
+	if err != nil {
+		return err
+	}
+	h.Set("ETag", `"`+tag+suffix+`"`)
+
+	// ServeContent answers conditional and range requests, Last-Modified is left out for a zero
+	// modification time, as embedded files have
+	http.ServeContent(w, r, name, info.ModTime(), content)
+
+	return nil
+}
+
+// etag - returns the content hash of the file, hashing it again only when it has changed
+func (me *Server) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if v, ok := me.etags.Load(name); ok {
+		if e := v.(etag); e.size == info.Size() && e.modTime.Equal(info.ModTime()) {
+			return e.value, nil
+		}
+	}
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	value := hex.EncodeToString(sum.Sum(nil)[:16])
+	me.etags.Store(name, etag{size: info.Size(), modTime: info.ModTime(), value: value})
+
+	return value, nil
+}
+
+// fingerprinted - tells whether the name carries a content hash, a hash holds at least one digit
+// so that words like settings.html are not taken for one
+func fingerprinted(base string) bool {
+	m := hashed.FindStringSubmatch(base)
+	return m != nil && strings.ContainsAny(m[1], "0123456789")
+}
+
+// accepts - tells whether the Accept-Encoding header allows the coding, q=0 refuses it. The coding
+// named in the header wins over *, so "*;q=0, gzip" accepts gzip
+func accepts(header, coding string) bool {
+	wildcard := false
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.TrimSpace(fields[0])
+		if name != coding && name != "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			if v := strings.TrimSpace(param); strings.HasPrefix(v, "q=") {
+				q, _ = strconv.ParseFloat(v[2:], 64)
+			}
+		}
+
+		if name == coding {
+			return q > 0
+		}
+		wildcard = q > 0
+	}
+
+	return wildcard
+}
@@ -0,0 +1,312 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// files - a small site with precompressed, fingerprinted and hidden files
+func files() fstest.MapFS {
+	modTime := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	file := func(data string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(data), ModTime: modTime}
+	}
+
+	return fstest.MapFS{
+		"index.html":         file("<h1>home</h1>"),
+		"app.js":             file("console.log('app')"),
+		"app.js.gz":          file("gzipped app"),
+		"app.js.br":          file("brotli app"),
+		"app.3f9a1c2e.js":    file("hashed"),
+		"docs/index.html":    file("<h1>docs</h1>"),
+		".env":               file("SECRET=1"),
+		"docs/.git/config":   file("[core]"),
+		"empty/.keep":        file(""),
+		"styles/site.css":    file("body{}"),
+		"styles/site.css.gz": file("gzipped css"),
+	}
+}
+
+// get - sends a GET request with the headers to the server
+func get(s *Server, target string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		prefix, path string
+		want         string
+		ok           bool
+	}{
+		{"/assets", "/assets", index, true},
+		{"/assets/", "/assets/", index, true},
+		{"/assets", "/assets/app.js", "app.js", true},
+		{"/assets", "/assets/docs/", "docs", true},
+		{"/assets", "/assets/../app.js", "app.js", true},
+		// the prefix ends on a segment boundary
+		{"/assets", "/assetsfoo", "", false},
+		{"/assets", "/assetsfoo/app.js", "", false},
+		{"/assets", "/other/app.js", "", false},
+		{"/", "/app.js", "app.js", true},
+		{"/", "/", index, true},
+		// hidden files and folders are never served
+		{"/assets", "/assets/.env", "", false},
+		{"/assets", "/assets/docs/.git/config", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix+" "+tt.path, func(t *testing.T) {
+			name, ok := New(files(), tt.prefix, false).name(tt.path)
+			if name != tt.want || ok != tt.ok {
+				t.Errorf("expected %q %v, got %q %v", tt.want, tt.ok, name, ok)
+			}
+		})
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.5", true},
+		{"GZIP", false},
+		{"gzip;q=0", false},
+		{"gzip;q=0.0, *", false},
+		{"*", true},
+		{"*;q=0", false},
+		// the coding named in the header wins over the wildcard, in any order
+		{"*;q=0, gzip", true},
+		{"gzip, *;q=0", true},
+		{"br, deflate", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := accepts(tt.header, "gzip"); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFingerprinted(t *testing.T) {
+	tests := []struct {
+		base string
+		want bool
+	}{
+		{"app.3f9a1c2e.js", true},
+		{"index-B7x2kQ9a.css", true},
+		{"chunk.0123456789abcdef.js", true},
+		{"app.js", false},
+		{"settings.html", false},
+		{"vendor.abcdefgh.js", false},
+		{"app.3f9a1c.js", false},
+		{"app.3f9a1c2e", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.base, func(t *testing.T) {
+			if got := fingerprinted(tt.base); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestServe(t *testing.T) {
+	s := New(files(), "/assets", false)
+
+	tests := []struct {
+		name  string
+		path  string
+		code  int
+		body  string
+		ctype string
+		cache string
+	}{
+		{"root index", "/assets/", http.StatusOK, "<h1>home</h1>", "text/html; charset=utf-8", cacheRevalidate},
+		{"file", "/assets/app.js", http.StatusOK, "console.log('app')", "text/javascript; charset=utf-8", cacheRevalidate},
+		{"fingerprinted file", "/assets/app.3f9a1c2e.js", http.StatusOK, "hashed", "text/javascript; charset=utf-8", cacheImmutable},
+		{"directory index", "/assets/docs/", http.StatusOK, "<h1>docs</h1>", "text/html; charset=utf-8", cacheRevalidate},
+		{"directory without index", "/assets/empty", http.StatusNotFound, "", "", ""},
+		{"missing file", "/assets/missing.js", http.StatusNotFound, "", "", ""},
+		{"unknown route", "/assets/some/route", http.StatusNotFound, "", "", ""},
+		{"hidden file", "/assets/.env", http.StatusNotFound, "", "", ""},
+		{"hidden folder", "/assets/docs/.git/config", http.StatusNotFound, "", "", ""},
+		{"sibling prefix", "/assetsfoo/app.js", http.StatusNotFound, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(s, tt.path)
+			if rec.Code != tt.code {
+				t.Fatalf("expected status %d, got %d", tt.code, rec.Code)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			if got := rec.Body.String(); got != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, got)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.ctype {
+				t.Errorf("expected Content-Type %q, got %q", tt.ctype, got)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != tt.cache {
+				t.Errorf("expected Cache-Control %q, got %q", tt.cache, got)
+			}
+			if rec.Header().Get("ETag") == "" {
+				t.Error("expected an ETag")
+			}
+		})
+	}
+}
+
+func TestMethods(t *testing.T) {
+	s := New(files(), "/", false)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/app.js", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("expected 405 with the allowed methods, got %d %v", rec.Code, rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/app.js", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 || rec.Header().Get("Content-Length") != "18" {
+		t.Errorf("expected the headers only, got %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+}
+
+func TestETag(t *testing.T) {
+	fsys := files()
+	s := New(fsys, "/", false)
+
+	tag := get(s, "/app.js").Header().Get("ETag")
+	if len(tag) != 34 || tag[0] != '"' || tag[33] != '"' {
+		t.Fatalf("expected a quoted content hash, got %s", tag)
+	}
+	if again := get(s, "/app.js").Header().Get("ETag"); again != tag {
+		t.Errorf("expected a stable ETag, got %s then %s", tag, again)
+	}
+
+	if rec := get(s, "/app.js", "If-None-Match", tag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("expected 304, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := get(s, "/app.js", "If-None-Match", `"other"`); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for another ETag, got %d", rec.Code)
+	}
+
+	// the file changed, its hash is computed again
+	fsys["app.js"] = &fstest.MapFile{Data: []byte("console.log('v2')"), ModTime: time.Now()}
+	if changed := get(s, "/app.js").Header().Get("ETag"); changed == tag {
+		t.Error("expected a new ETag after the file changed")
+	}
+	if rec := get(s, "/app.js", "If-None-Match", tag); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for the old ETag, got %d", rec.Code)
+	}
+
+	// each variant carries its own ETag
+	if gz := get(s, "/app.js", "Accept-Encoding", "gzip").Header().Get("ETag"); gz == tag || gz[len(gz)-6:] != `-gzip"` {
+		t.Errorf("expected the ETag of the gzip variant, got %s", gz)
+	}
+}
+
+func TestRange(t *testing.T) {
+	s := New(files(), "/", false)
+
+	rec := get(s, "/app.js", "Range", "bytes=0-6")
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "console" {
+		t.Fatalf("expected the first bytes, got %d %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Range"); got != "bytes 0-6/18" {
+		t.Errorf("unexpected Content-Range %s", got)
+	}
+
+	if rec = get(s, "/app.js", "Range", "bytes=100-"); rec.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("expected 416, got %d", rec.Code)
+	}
+}
+
+func TestPrecompressed(t *testing.T) {
+	s := New(files(), "/", false)
+
+	tests := []struct {
+		path, accept string
+		encoding     string
+		body         string
+	}{
+		{"/app.js", "", "", "console.log('app')"},
+		{"/app.js", "gzip", "gzip", "gzipped app"},
+		{"/app.js", "gzip, deflate, br", "br", "brotli app"},
+		{"/app.js", "br;q=0, gzip", "gzip", "gzipped app"},
+		{"/app.js", "*", "br", "brotli app"},
+		{"/app.js", "*;q=0, gzip", "gzip", "gzipped app"},
+		{"/app.js", "*;q=0", "", "console.log('app')"},
+		{"/styles/site.css", "br, gzip", "gzip", "gzipped css"},
+		{"/index.html", "br, gzip", "", "<h1>home</h1>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.accept, func(t *testing.T) {
+			rec := get(s, tt.path, "Accept-Encoding", tt.accept)
+			if got := rec.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Errorf("expected encoding %q, got %q", tt.encoding, got)
+			}
+			if got := rec.Body.String(); got != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, got)
+			}
+
+			// the type is the one of the original file, caches key on the encoding when there is a variant
+			if got := rec.Header().Get("Content-Type"); got == "application/gzip" || got == "" {
+				t.Errorf("unexpected Content-Type %q", got)
+			}
+			if vary := rec.Header().Get("Vary"); (vary == "Accept-Encoding") != (tt.path != "/index.html") {
+				t.Errorf("unexpected Vary %q", vary)
+			}
+		})
+	}
+}
+
+func TestSPA(t *testing.T) {
+	s := New(files(), "/app", true)
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/app/some/route", http.StatusOK, "<h1>home</h1>"},
+		{"/app/settings", http.StatusOK, "<h1>home</h1>"},
+		{"/app/app.js", http.StatusOK, "console.log('app')"},
+		{"/app/docs/", http.StatusOK, "<h1>docs</h1>"},
+		// a missing asset is not answered with the page
+		{"/app/missing.js", http.StatusNotFound, ""},
+		{"/app/.env", http.StatusNotFound, ""},
+		{"/application", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := get(s, tt.path)
+			if rec.Code != tt.code {
+				t.Fatalf("expected status %d, got %d", tt.code, rec.Code)
+			}
+			if tt.code == http.StatusOK && rec.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, rec.Body.String())
+			}
+		})
+	}
+}
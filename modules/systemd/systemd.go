@@ -0,0 +1,113 @@
+package systemd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const (
+	// listenFdsStart is the first file descriptor passed by systemd
+	listenFdsStart = 3
+
+	EnvListenPID     = "LISTEN_PID"
+	EnvListenFDs     = "LISTEN_FDS"
+	EnvListenFDNames = "LISTEN_FDNAMES"
+)
+
+// Socket is a listener inherited from the service manager
+type Socket struct {
+	Name     string
+	Listener net.Listener
+	claimed  bool
+}
+
+var (
+	once    sync.Once
+	mu      sync.Mutex
+	sockets []*Socket
+	initErr error
+)
+
+// Sockets returns the listeners passed with socket activation, they are read once per process
+func Sockets() ([]*Socket, error) {
+	once.Do(func() {
+		sockets, initErr = inherit(listenFdsStart)
+	})
+
+	return sockets, initErr
+}
+
+// Take claims an inherited listener by its LISTEN_FDNAMES name,
+// an empty name claims the first listener not taken yet
+func Take(name string) (net.Listener, error) {
+	all, err := Sockets()
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, s := range all {
+		if s.claimed || (name != "" && s.Name != name) {
+			continue
+		}
+		s.claimed = true
+		return s.Listener, nil
+	}
+
+	if name == "" {
+		return nil, errors.New("no inherited socket left")
+	}
+
+	return nil, fmt.Errorf("no inherited socket named `%s`", name)
+}
+
+// inherit converts the passed file descriptors, numbered from start, to listeners and clears
+// the environment so the sockets are not inherited again by child processes
+func inherit(start int) ([]*Socket, error) {
+	pid, err := strconv.Atoi(os.Getenv(EnvListenPID))
+	if err != nil || pid != os.Getpid() {
+		return nil, errors.New("no sockets passed by the service manager")
+	}
+
+	count, err := strconv.Atoi(os.Getenv(EnvListenFDs))
+	if err != nil || count == 0 {
+		return nil, errors.New("no sockets passed by the service manager")
+	}
+
+	names := strings.Split(os.Getenv(EnvListenFDNames), ":")
+
+	os.Unsetenv(EnvListenPID)
+	os.Unsetenv(EnvListenFDs)
+	os.Unsetenv(EnvListenFDNames)
+
+	result := make([]*Socket, 0, count)
+	for i := 0; i < count; i++ {
+		fd := start + i
+		syscall.CloseOnExec(fd)
+
+		s := &Socket{Name: strconv.Itoa(fd)}
+		if i < len(names) && names[i] != "" {
+			s.Name = names[i]
+		}
+
+		f := os.NewFile(uintptr(fd), s.Name)
+		if s.Listener, err = net.FileListener(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("inherited socket `%s` is not a listener: %v", s.Name, err)
+		}
+		// the listener holds its own duplicate of the descriptor
+		f.Close()
+
+		result = append(result, s)
+	}
+
+	return result, nil
+}
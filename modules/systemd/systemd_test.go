@@ -0,0 +1,142 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// firstFd - a descriptor number far above the ones the test process uses
+const firstFd = 100
+
+// passListeners - places listening sockets on the descriptors from firstFd, as systemd does from 3
+func passListeners(t *testing.T, count int) []net.Addr {
+	t.Helper()
+
+	addrs := make([]net.Addr, 0, count)
+	for i := 0; i < count; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := ln.(*net.TCPListener).File()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = syscall.Dup2(int(f.Fd()), firstFd+i); err != nil {
+			t.Fatal(err)
+		}
+		addrs = append(addrs, ln.Addr())
+		f.Close()
+		ln.Close()
+	}
+
+	return addrs
+}
+
+// setEnv - sets the socket activation variables, the pid defaults to the test process
+func setEnv(t *testing.T, pid, fds, names string) {
+	if pid == "" {
+		pid = strconv.Itoa(os.Getpid())
+	}
+	t.Setenv(EnvListenPID, pid)
+	t.Setenv(EnvListenFDs, fds)
+	t.Setenv(EnvListenFDNames, names)
+}
+
+func TestInheritChecksEnvironment(t *testing.T) {
+	tests := []struct {
+		name, pid, fds string
+	}{
+		{"other process", "1", "1"},
+		{"invalid pid", "x", "1"},
+		{"no descriptors", "", "0"},
+		{"invalid count", "", "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.pid, tt.fds, "")
+			if _, err := inherit(firstFd); err == nil || err.Error() != "no sockets passed by the service manager" {
+				t.Errorf("expected no sockets, got %v", err)
+			}
+		})
+	}
+}
+
+func TestInheritNotAListener(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = syscall.Dup2(int(f.Fd()), firstFd); err != nil {
+		t.Fatal(err)
+	}
+
+	setEnv(t, "", "1", "web")
+	if _, err = inherit(firstFd); err == nil || !strings.Contains(err.Error(), "inherited socket `web` is not a listener") {
+		t.Errorf("expected the file to be refused, got %v", err)
+	}
+}
+
+func TestTake(t *testing.T) {
+	addrs := passListeners(t, 3)
+	// the last descriptor has no name, it is named after its number
+	setEnv(t, "", "3", "http:admin")
+
+	all, err := inherit(firstFd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[0].Name != "http" || all[1].Name != "admin" || all[2].Name != strconv.Itoa(firstFd+2) {
+		t.Fatalf("unexpected sockets %+v", all)
+	}
+	for _, key := range []string{EnvListenPID, EnvListenFDs, EnvListenFDNames} {
+		if _, ok := os.LookupEnv(key); ok {
+			t.Errorf("expected %s to be cleared for child processes", key)
+		}
+	}
+
+	// the test takes the place of the service manager, Sockets reads the descriptors once
+	once.Do(func() {})
+	mu.Lock()
+	sockets, initErr = all, nil
+	mu.Unlock()
+
+	tests := []struct {
+		name    string
+		want    net.Addr
+		problem string
+	}{
+		{"admin", addrs[1], ""},
+		{"admin", nil, "no inherited socket named `admin`"},
+		{"https", nil, "no inherited socket named `https`"},
+		// an empty name takes the first socket not claimed yet
+		{"", addrs[0], ""},
+		{"", addrs[2], ""},
+		{"", nil, "no inherited socket left"},
+		{"http", nil, "no inherited socket named `http`"},
+	}
+	for _, tt := range tests {
+		ln, err := Take(tt.name)
+		switch {
+		case tt.problem != "" && (err == nil || err.Error() != tt.problem):
+			t.Errorf("%q: expected `%s`, got %v", tt.name, tt.problem, err)
+		case tt.problem == "" && err != nil:
+			t.Errorf("%q: unexpected error %v", tt.name, err)
+		case tt.problem == "" && ln.Addr().String() != tt.want.String():
+			t.Errorf("%q: expected the socket on %s, got %s", tt.name, tt.want, ln.Addr())
+		}
+	}
+
+	// the inherited socket accepts connections
+	c, err := net.Dial("tcp", addrs[1].String())
+	if err != nil {
+		t.Fatalf("inherited socket not listening: %v", err)
+	}
+	c.Close()
+}
@@ -0,0 +1,213 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// Exporter sends a batch of ended spans to a tracing backend
+	Exporter interface {
+		Export(ctx context.Context, service string, spans []*Span) error
+	}
+
+	// FileExporter appends every batch to a file as one OTLP/JSON document per line
+	FileExporter struct {
+		sync.Mutex
+		Path string
+	}
+
+	// HTTPExporter posts every batch as OTLP/JSON to a collector, e.g. http://localhost:4318/v1/traces
+	HTTPExporter struct {
+		Endpoint string
+		Client   *http.Client
+	}
+
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		TraceState        string          `json:"traceState,omitempty"`
+		Name              string          `json:"name"`
+		Kind              SpanKind        `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+
+	otlpScopeSpans struct {
+		Scope struct {
+			Name string `json:"name"`
+		} `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+
+	otlpResourceSpans struct {
+		Resource struct {
+			Attributes []otlpAttribute `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+)
+
+const (
+	scopeName = "httpframwork"
+
+	statusOK    = 1
+	statusError = 2
+)
+
+// Export method - appends the batch to the file, creating it and its folder when missing
+func (me *FileExporter) Export(_ context.Context, service string, spans []*Span) error {
+	b, err := encode(service, spans)
+	if err != nil {
+		return err
+	}
+
+	me.Lock()
+	defer me.Unlock()
+
+	if err = os.MkdirAll(filepath.Dir(me.Path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(me.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(b, '\n'))
+
+	return err
+}
+
+// Export method - posts the batch to the collector
+func (me *HTTPExporter) Export(ctx context.Context, service string, spans []*Span) error {
+	b, err := encode(service, spans)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, me.Endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := me.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("collector answered %d: %s", res.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
+
+// encode - converts the spans to an OTLP/JSON export request
+func encode(service string, spans []*Span) ([]byte, error) {
+	scope := otlpScopeSpans{Spans: make([]otlpSpan, 0, len(spans))}
+	scope.Scope.Name = scopeName
+
+	for _, s := range spans {
+		s.Lock()
+		span := otlpSpan{
+			TraceID:           s.Context.TraceID.String(),
+			SpanID:            s.Context.SpanID.String(),
+			TraceState:        s.Context.State,
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: unixNano(s.Start),
+			EndTimeUnixNano:   unixNano(s.End),
+			Attributes:        attributes(s.Attributes),
+			Status:            otlpStatus{Code: statusOK},
+		}
+		if s.Parent != (SpanID{}) {
+			span.ParentSpanID = s.Parent.String()
+		}
+		if s.Err != "" {
+			span.Status = otlpStatus{Code: statusError, Message: s.Err}
+		}
+		s.Unlock()
+
+		scope.Spans = append(scope.Spans, span)
+	}
+
+	resource := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scope}}
+	resource.Resource.Attributes = attributes(map[string]interface{}{"service.name": service})
+
+	return json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{resource}})
+}
+
+// attributes - converts the values to OTLP attributes sorted by key, unknown types are formatted as strings
+func attributes(values map[string]interface{}) []otlpAttribute {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]otlpAttribute, 0, len(keys))
+	for _, k := range keys {
+		var v otlpValue
+		switch value := values[k].(type) {
+		case bool:
+			v.BoolValue = &value
+		case int:
+			s := strconv.Itoa(value)
+			v.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(value, 10)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &value
+		default:
+			s := fmt.Sprint(value)
+			v.StringValue = &s
+		}
+		attrs = append(attrs, otlpAttribute{Key: k, Value: v})
+	}
+
+	return attrs
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,170 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSpans - returns a root span and a failed child with attributes of every type
+func testSpans() []*Span {
+	tracer := newTracer(nil)
+	root := tracer.start("GET /users", KindServer, SpanContext{TraceID: TraceID{1}, Sampled: true, State: "vendor=1"}, false)
+	child := root.StartChild("query")
+	child.SetAttribute("db.rows", 3)
+	child.SetAttribute("db.cached", false)
+	child.SetAttribute("db.ratio", 0.5)
+	child.SetAttribute("db.statement", "SELECT 1")
+	child.SetError(errors.New("deadlock"))
+
+	start := time.Unix(1, 500)
+	for _, s := range []*Span{root, child} {
+		s.Start, s.End = start, start.Add(time.Millisecond)
+		s.ended = true
+	}
+
+	return []*Span{root, child}
+}
+
+// decodeRequest - parses one OTLP/JSON export request
+func decodeRequest(t *testing.T, b []byte) otlpRequest {
+	t.Helper()
+
+	var req otlpRequest
+	if err := json.Unmarshal(b, &req); err != nil {
+		t.Fatalf("invalid OTLP/JSON %s: %v", b, err)
+	}
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("expected one resource and scope, got %s", b)
+	}
+
+	return req
+}
+
+func TestEncode(t *testing.T) {
+	spans := testSpans()
+	b, err := encode("users", spans)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := decodeRequest(t, b)
+	resource := req.ResourceSpans[0]
+	if attrs := resource.Resource.Attributes; len(attrs) != 1 || attrs[0].Key != "service.name" || *attrs[0].Value.StringValue != "users" {
+		t.Errorf("unexpected resource attributes %+v", attrs)
+	}
+
+	scope := resource.ScopeSpans[0]
+	if scope.Scope.Name != scopeName || len(scope.Spans) != 2 {
+		t.Fatalf("unexpected scope %s", b)
+	}
+
+	root, child := scope.Spans[0], scope.Spans[1]
+	if root.TraceID != spans[0].TraceID() || child.TraceID != root.TraceID {
+		t.Errorf("spans must share the trace id, got %s and %s", root.TraceID, child.TraceID)
+	}
+	if root.ParentSpanID != "" || child.ParentSpanID != root.SpanID {
+		t.Errorf("expected the child under the root, got parents `%s` and `%s`", root.ParentSpanID, child.ParentSpanID)
+	}
+	if root.Kind != KindServer || child.Kind != KindInternal || root.TraceState != "vendor=1" {
+		t.Errorf("unexpected kinds or trace state %+v %+v", root, child)
+	}
+	if root.StartTimeUnixNano != "1000000500" || root.EndTimeUnixNano != "1001000500" {
+		t.Errorf("unexpected times %s %s", root.StartTimeUnixNano, root.EndTimeUnixNano)
+	}
+	if root.Status != (otlpStatus{Code: statusOK}) || child.Status != (otlpStatus{Code: statusError, Message: "deadlock"}) {
+		t.Errorf("unexpected statuses %+v %+v", root.Status, child.Status)
+	}
+
+	// attributes are sorted by key and typed
+	attrs := child.Attributes
+	if len(attrs) != 4 {
+		t.Fatalf("expected 4 attributes, got %+v", attrs)
+	}
+	if attrs[0].Key != "db.cached" || *attrs[0].Value.BoolValue != false {
+		t.Errorf("unexpected bool attribute %+v", attrs[0])
+	}
+	if attrs[1].Key != "db.ratio" || *attrs[1].Value.DoubleValue != 0.5 {
+		t.Errorf("unexpected double attribute %+v", attrs[1])
+	}
+	if attrs[2].Key != "db.rows" || *attrs[2].Value.IntValue != "3" {
+		t.Errorf("unexpected int attribute %+v", attrs[2])
+	}
+	if attrs[3].Key != "db.statement" || *attrs[3].Value.StringValue != "SELECT 1" {
+		t.Errorf("unexpected string attribute %+v", attrs[3])
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces", "spans.json")
+	exporter := &FileExporter{Path: path}
+
+	for i := 0; i < 2; i++ {
+		if err := exporter.Export(context.Background(), "users", testSpans()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// one document per batch, appended
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		decodeRequest(t, scanner.Bytes())
+		lines++
+	}
+	if lines != 2 {
+		t.Errorf("expected 2 batches, got %d", lines)
+	}
+}
+
+func TestHTTPExporter(t *testing.T) {
+	var received []byte
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		received, _ = io.ReadAll(r.Body)
+	}))
+	defer collector.Close()
+
+	exporter := &HTTPExporter{Endpoint: collector.URL, Client: collector.Client()}
+	if err := exporter.Export(context.Background(), "users", testSpans()); err != nil {
+		t.Fatal(err)
+	}
+	if req := decodeRequest(t, received); len(req.ResourceSpans[0].ScopeSpans[0].Spans) != 2 {
+		t.Errorf("expected 2 spans, got %s", received)
+	}
+}
+
+func TestHTTPExporterErrors(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer collector.Close()
+
+	exporter := &HTTPExporter{Endpoint: collector.URL, Client: collector.Client()}
+	err := exporter.Export(context.Background(), "users", testSpans())
+	if err == nil || err.Error() != "collector answered 429: quota exceeded" {
+		t.Errorf("expected the collector answer, got %v", err)
+	}
+
+	collector.Close()
+	if err = exporter.Export(context.Background(), "users", testSpans()); err == nil || !strings.Contains(err.Error(), "connect") {
+		t.Errorf("expected a connection error, got %v", err)
+	}
+}
@@ -0,0 +1,380 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"httpframwork/modules/container"
+)
+
+type (
+	// TraceID identifies a trace across services
+	TraceID [16]byte
+
+	// SpanID identifies a span inside a trace
+	SpanID [8]byte
+
+	// SpanContext is the part of a span propagated to other services
+	SpanContext struct {
+		TraceID TraceID
+		SpanID  SpanID
+		Sampled bool
+		State   string
+	}
+
+	// SpanKind tells the role of the span in the trace, values follow OTLP
+	SpanKind int
+
+	// Span is a timed operation, methods are safe to call on a nil span
+	Span struct {
+		sync.Mutex
+		Name       string
+		Kind       SpanKind
+		Context    SpanContext
+		Parent     SpanID
+		Start      time.Time
+		End        time.Time
+		Attributes map[string]interface{}
+		Err        string
+		tracer     *Tracer
+		ended      bool
+	}
+
+	// Tracer starts spans and hands the sampled ones to the exporter
+	Tracer struct {
+		sync.Mutex
+		enabled     bool
+		service     string
+		sampleRatio float64
+		batchSize   int
+		exporter    Exporter
+		buffer      []*Span
+		flush       chan struct{}
+		dropped     int
+	}
+
+	spanKey struct{}
+)
+
+const (
+	InstanceKey = "Tracer"
+
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+
+	// maxBuffered bounds the spans kept while the exporter is unreachable
+	maxBuffered = 16
+)
+
+// GetRegistry function ...
+func GetRegistry() container.Registries {
+	obj := &Tracer{
+		flush: make(chan struct{}, 1),
+	}
+
+	return container.Registries{
+		container.Registry{
+			Key:   InstanceKey,
+			Value: obj,
+		},
+	}
+}
+
+// GetInstance function ...
+func GetInstance(c *container.Container) *Tracer {
+	return c.Get(InstanceKey).(*Tracer)
+}
+
+// Configure method - enables the tracer, a nil exporter keeps the spans local
+func (me *Tracer) Configure(service string, sampleRatio float64, batchSize int, exporter Exporter) *Tracer {
+	me.Lock()
+	defer me.Unlock()
+
+	me.enabled = true
+	me.service = service
+	me.sampleRatio = sampleRatio
+	me.batchSize = batchSize
+	me.exporter = exporter
+
+	return me
+}
+
+// Enabled method - tells whether spans are created
+func (me *Tracer) Enabled() bool {
+	me.Lock()
+	defer me.Unlock()
+
+	return me.enabled
+}
+
+// StartServer method - starts the span of an incoming request, continuing the trace of the caller
+// found in the traceparent and tracestate headers
+func (me *Tracer) StartServer(r *http.Request, name string) *Span {
+	parent, ok := Extract(r.Header)
+	if !ok {
+		parent = SpanContext{TraceID: newTraceID(), Sampled: me.sample()}
+	}
+
+	return me.start(name, KindServer, parent, ok)
+}
+
+// start - creates a span under parent, hasParent false makes it the root of the trace
+func (me *Tracer) start(name string, kind SpanKind, parent SpanContext, hasParent bool) *Span {
+	s := &Span{
+		Name:    name,
+		Kind:    kind,
+		Context: parent,
+		Start:   time.Now(),
+		tracer:  me,
+	}
+	s.Context.SpanID = newSpanID()
+	if hasParent {
+		s.Parent = parent.SpanID
+	}
+
+	return s
+}
+
+// sample - decides whether a new trace is exported
+func (me *Tracer) sample() bool {
+	me.Lock()
+	ratio := me.sampleRatio
+	me.Unlock()
+
+	if ratio >= 1 {
+		return true
+	}
+
+	var b [8]byte
+	rand.Read(b[:])
+
+	return float64(binary.BigEndian.Uint64(b[:])>>11)/(1<<53) < ratio
+}
+
+// enqueue - buffers an ended span until the next export
+func (me *Tracer) enqueue(s *Span) {
+	me.Lock()
+	defer me.Unlock()
+
+	if me.exporter == nil {
+		return
+	}
+
+	if len(me.buffer) >= me.batchSize*maxBuffered {
+		me.dropped++
+		return
+	}
+
+	me.buffer = append(me.buffer, s)
+	if len(me.buffer) >= me.batchSize {
+		select {
+		case me.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run method - exports the buffered spans every interval or when a batch is full, until ctx is done
+func (me *Tracer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-me.flush:
+		}
+
+		if err := me.Flush(ctx); err != nil {
+			log.Printf("failed to export spans with error `%v`\n", err)
+		}
+	}
+}
+
+// Flush method - exports the buffered spans, spans are kept for the next attempt when the export fails
+func (me *Tracer) Flush(ctx context.Context) (err error) {
+	me.Lock()
+	spans, exporter, service, dropped := me.buffer, me.exporter, me.service, me.dropped
+	me.buffer, me.dropped = nil, 0
+	me.Unlock()
+
+	if len(spans) == 0 || exporter == nil {
+		return
+	}
+
+	if dropped > 0 {
+		err = fmt.Errorf("dropped %d spans while the exporter was unavailable", dropped)
+	}
+
+	if eErr := exporter.Export(ctx, service, spans); eErr != nil {
+		me.Lock()
+		if len(me.buffer)+len(spans) <= me.batchSize*maxBuffered {
+			me.buffer = append(spans, me.buffer...)
+		}
+		me.Unlock()
+		return eErr
+	}
+
+	return
+}
+
+// StartChild method - starts an internal span under the span
+func (me *Span) StartChild(name string) *Span {
+	if me == nil {
+		return nil
+	}
+
+	return me.tracer.start(name, KindInternal, me.Context, true)
+}
+
+// SetAttribute method - records a string, bool, integer or float value on the span
+func (me *Span) SetAttribute(key string, value interface{}) {
+	if me == nil {
+		return
+	}
+
+	me.Lock()
+	defer me.Unlock()
+
+	if me.Attributes == nil {
+		me.Attributes = make(map[string]interface{})
+	}
+	me.Attributes[key] = value
+}
+
+// SetError method - marks the span as failed
+func (me *Span) SetError(err error) {
+	if me == nil || err == nil {
+		return
+	}
+
+	me.Lock()
+	me.Err = err.Error()
+	me.Unlock()
+}
+
+// Finish method - ends the span and queues it for export when it is sampled, calling it again does nothing
+func (me *Span) Finish() {
+	if me == nil {
+		return
+	}
+
+	me.Lock()
+	if me.ended {
+		me.Unlock()
+		return
+	}
+	me.ended = true
+	me.End = time.Now()
+	me.Unlock()
+
+	if me.Context.Sampled {
+		me.tracer.enqueue(me)
+	}
+}
+
+// TraceID method - returns the hex trace id, empty for a nil span
+func (me *Span) TraceID() string {
+	if me == nil {
+		return ""
+	}
+
+	return me.Context.TraceID.String()
+}
+
+// Inject method - adds the traceparent and tracestate headers of the span to outgoing headers
+func (me *Span) Inject(h http.Header) {
+	if me == nil {
+		return
+	}
+
+	h.Set(TraceparentHeader, me.Context.Traceparent())
+	if me.Context.State != "" {
+		h.Set(TracestateHeader, me.Context.State)
+	}
+}
+
+// Traceparent method - formats the context as a version 00 traceparent header
+func (me SpanContext) Traceparent() string {
+	flags := "00"
+	if me.Sampled {
+		flags = "01"
+	}
+
+	return "00-" + me.TraceID.String() + "-" + me.SpanID.String() + "-" + flags
+}
+
+// Extract function - reads the caller span from the traceparent and tracestate headers
+func Extract(h http.Header) (sc SpanContext, ok bool) {
+	parts := strings.Split(strings.TrimSpace(h.Get(TraceparentHeader)), "-")
+	// future versions may append fields, version ff is invalid
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return
+	}
+
+	if !decodeHex(parts[1], sc.TraceID[:]) || !decodeHex(parts[2], sc.SpanID[:]) || len(parts[3]) != 2 {
+		return
+	}
+	if sc.TraceID == (TraceID{}) || sc.SpanID == (SpanID{}) {
+		return
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return
+	}
+
+	sc.Sampled = flags[0]&1 == 1
+	sc.State = strings.Join(h.Values(TracestateHeader), ",")
+
+	return sc, true
+}
+
+// ContextWithSpan function - returns a copy of ctx carrying the span
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// SpanFromContext function - returns the span carried by ctx, nil when there is none
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+func (me TraceID) String() string { return hex.EncodeToString(me[:]) }
+
+func (me SpanID) String() string { return hex.EncodeToString(me[:]) }
+
+// decodeHex - decodes lower case hex of exactly the size of dst
+func decodeHex(s string, dst []byte) bool {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+
+	return err == nil
+}
+
+func newTraceID() (id TraceID) {
+	rand.Read(id[:])
+	return
+}
+
+func newSpanID() (id SpanID) {
+	rand.Read(id[:])
+	return
+}
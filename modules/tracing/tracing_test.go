@@ -0,0 +1,269 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeExporter - records the exported batches, failing while err is set
+type fakeExporter struct {
+	sync.Mutex
+	batches [][]*Span
+	err     error
+}
+
+func (me *fakeExporter) Export(_ context.Context, _ string, spans []*Span) error {
+	me.Lock()
+	defer me.Unlock()
+
+	if me.err != nil {
+		return me.err
+	}
+	me.batches = append(me.batches, spans)
+
+	return nil
+}
+
+// exported - returns the number of batches and spans exported so far
+func (me *fakeExporter) exported() (batches, spans int) {
+	me.Lock()
+	defer me.Unlock()
+
+	for _, b := range me.batches {
+		spans += len(b)
+	}
+
+	return len(me.batches), spans
+}
+
+// newTracer - returns a tracer as registered in the container, configured with batches of 2
+func newTracer(exporter Exporter) *Tracer {
+	return GetRegistry()[0].Value.(*Tracer).Configure("test", 1, 2, exporter)
+}
+
+// finishSpans - starts and finishes n sampled root spans
+func finishSpans(tracer *Tracer, n int) {
+	for i := 0; i < n; i++ {
+		tracer.StartServer(httptest.NewRequest(http.MethodGet, "/", nil), "GET /").Finish()
+	}
+}
+
+func TestExtract(t *testing.T) {
+	const (
+		trace = "4bf92f3577b34da6a3ce929d0e0e4736"
+		span  = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name        string
+		traceparent string
+		ok          bool
+		sampled     bool
+	}{
+		{"sampled", "00-" + trace + "-" + span + "-01", true, true},
+		{"not sampled", "00-" + trace + "-" + span + "-00", true, false},
+		{"future version with more fields", "01-" + trace + "-" + span + "-01-extra", true, true},
+		{"version 00 with more fields", "00-" + trace + "-" + span + "-01-extra", false, false},
+		{"invalid version", "ff-" + trace + "-" + span + "-01", false, false},
+		{"upper case", "00-" + "4BF92F3577B34DA6A3CE929D0E0E4736" + "-" + span + "-01", false, false},
+		{"zero trace id", "00-00000000000000000000000000000000-" + span + "-01", false, false},
+		{"zero span id", "00-" + trace + "-0000000000000000-01", false, false},
+		{"short span id", "00-" + trace + "-00f067aa-01", false, false},
+		{"bad flags", "00-" + trace + "-" + span + "-zz", false, false},
+		{"missing", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set(TraceparentHeader, tt.traceparent)
+			h.Add(TracestateHeader, "a=1")
+			h.Add(TracestateHeader, "b=2")
+
+			sc, ok := Extract(h)
+			if ok != tt.ok {
+				t.Fatalf("expected ok %v, got %v", tt.ok, ok)
+			}
+			if !ok {
+				return
+			}
+			if sc.TraceID.String() != trace || sc.SpanID.String() != span || sc.Sampled != tt.sampled {
+				t.Errorf("unexpected context %+v", sc)
+			}
+			if sc.State != "a=1,b=2" {
+				t.Errorf("expected the joined trace state, got `%s`", sc.State)
+			}
+		})
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	tracer := newTracer(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(TracestateHeader, "vendor=1")
+
+	server := tracer.StartServer(req, "GET /")
+	if server.TraceID() != "4bf92f3577b34da6a3ce929d0e0e4736" || server.Parent.String() != "00f067aa0ba902b7" {
+		t.Fatalf("the caller trace was not continued: %+v", server)
+	}
+
+	child := server.StartChild("call")
+	out := http.Header{}
+	child.Inject(out)
+
+	sc, ok := Extract(out)
+	if !ok || sc.TraceID != server.Context.TraceID || sc.SpanID != child.Context.SpanID || !sc.Sampled || sc.State != "vendor=1" {
+		t.Errorf("injected headers %v do not carry the child span", out)
+	}
+
+	// a request without a caller starts a new trace
+	root := tracer.StartServer(httptest.NewRequest(http.MethodGet, "/", nil), "GET /")
+	if root.Context.TraceID == server.Context.TraceID || root.Parent != (SpanID{}) {
+		t.Errorf("expected a new root span, got %+v", root)
+	}
+}
+
+func TestNilSpan(t *testing.T) {
+	var s *Span
+
+	s.SetAttribute("key", "value")
+	s.SetError(errors.New("failed"))
+	s.Inject(http.Header{})
+	s.Finish()
+	if s.StartChild("child") != nil || s.TraceID() != "" {
+		t.Error("a nil span must stay nil")
+	}
+	if SpanFromContext(context.Background()) != nil {
+		t.Error("expected no span in an empty context")
+	}
+	if s := (&Span{Name: "x"}); SpanFromContext(ContextWithSpan(context.Background(), s)) != s {
+		t.Error("expected the span carried by the context")
+	}
+}
+
+func TestSampling(t *testing.T) {
+	exporter := &fakeExporter{}
+	tracer := newTracer(exporter).Configure("test", 0, 2, exporter)
+
+	finishSpans(tracer, 10)
+	tracer.Flush(context.Background())
+	if _, spans := exporter.exported(); spans != 0 {
+		t.Errorf("expected no exported span with a ratio of 0, got %d", spans)
+	}
+
+	// the sampling decision of the caller wins over the ratio
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	tracer.StartServer(req, "GET /").Finish()
+	tracer.Flush(context.Background())
+	if _, spans := exporter.exported(); spans != 1 {
+		t.Errorf("expected the span sampled by the caller, got %d", spans)
+	}
+}
+
+func TestFinishOnce(t *testing.T) {
+	exporter := &fakeExporter{}
+	tracer := newTracer(exporter)
+
+	s := tracer.StartServer(httptest.NewRequest(http.MethodGet, "/", nil), "GET /")
+	s.Finish()
+	s.Finish()
+
+	tracer.Flush(context.Background())
+	if batches, spans := exporter.exported(); batches != 1 || spans != 1 {
+		t.Errorf("expected one span exported once, got %d spans in %d batches", spans, batches)
+	}
+}
+
+func TestRunExportsFullBatches(t *testing.T) {
+	exporter := &fakeExporter{}
+	tracer := newTracer(exporter)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tracer.Run(ctx, time.Hour)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// a full batch is exported without waiting for the interval
+	finishSpans(tracer, 2)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, spans := exporter.exported(); spans == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("full batch not exported")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRunExportsOnInterval(t *testing.T) {
+	exporter := &fakeExporter{}
+	tracer := newTracer(exporter)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	finishSpans(tracer, 1)
+	tracer.Run(ctx, 20*time.Millisecond)
+
+	if _, spans := exporter.exported(); spans != 1 {
+		t.Errorf("expected the partial batch exported on the interval, got %d spans", spans)
+	}
+}
+
+func TestFlushRetriesFailedExports(t *testing.T) {
+	exporter := &fakeExporter{err: errors.New("unreachable")}
+	tracer := newTracer(exporter)
+
+	finishSpans(tracer, 3)
+	if err := tracer.Flush(context.Background()); err == nil || err.Error() != "unreachable" {
+		t.Fatalf("expected the export error, got %v", err)
+	}
+
+	// the spans are kept for the next attempt
+	exporter.Lock()
+	exporter.err = nil
+	exporter.Unlock()
+	finishSpans(tracer, 1)
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if batches, spans := exporter.exported(); batches != 1 || spans != 4 {
+		t.Errorf("expected 4 spans in 1 batch, got %d in %d", spans, batches)
+	}
+}
+
+func TestBufferIsBounded(t *testing.T) {
+	exporter := &fakeExporter{err: errors.New("unreachable")}
+	tracer := newTracer(exporter)
+
+	limit := 2 * maxBuffered
+	finishSpans(tracer, limit+5)
+
+	exporter.Lock()
+	exporter.err = nil
+	exporter.Unlock()
+
+	err := tracer.Flush(context.Background())
+	if err == nil || err.Error() != "dropped 5 spans while the exporter was unavailable" {
+		t.Errorf("expected the dropped spans to be reported, got %v", err)
+	}
+	if _, spans := exporter.exported(); spans != limit {
+		t.Errorf("expected %d buffered spans, got %d", limit, spans)
+	}
+}
@@ -0,0 +1,160 @@
+package upgrade
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EnvListeners holds the comma separated names of the handed over listeners,
+	// their descriptors start at 3 in the same order
+	EnvListeners = "UPGRADE_LISTENERS"
+	// EnvReadyFD holds the descriptor the new process writes to once it serves requests
+	EnvReadyFD = "UPGRADE_READY_FD"
+
+	firstFD = 3
+)
+
+// Listener is a named listening socket handed over to the new process
+type Listener struct {
+	Name string
+	File *os.File
+}
+
+var (
+	once      sync.Once
+	mu        sync.Mutex
+	inherited map[string]net.Listener
+	readyFile *os.File
+)
+
+// Handoff starts path with the given listeners and waits until the new process reports ready.
+// The new process is killed when it does not get ready within the timeout.
+func Handoff(path string, args []string, listeners []Listener, timeout time.Duration) (*os.Process, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	names := make([]string, 0, len(listeners))
+	files := make([]*os.File, 0, len(listeners)+1)
+	for _, l := range listeners {
+		names = append(names, l.Name)
+		files = append(files, l.File)
+	}
+	files = append(files, w)
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(os.Environ(),
+		EnvListeners+"="+strings.Join(names, ","),
+		EnvReadyFD+"="+strconv.Itoa(firstFD+len(listeners)),
+	)
+
+	err = cmd.Start()
+	// the new process holds its own copy of the write end
+	w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		// EOF means the new process exited without reporting ready
+		_, err := r.Read(make([]byte, 1))
+		ready <- err
+	}()
+
+	select {
+	case err = <-ready:
+		if err == nil {
+			return cmd.Process, nil
+		}
+		err = errors.New("new process exited before it was ready")
+	case <-time.After(timeout):
+		err = fmt.Errorf("new process not ready after %s", timeout)
+	}
+
+	cmd.Process.Kill()
+	cmd.Wait()
+
+	return nil, err
+}
+
+// IsChild reports whether the process was started by a handoff
+func IsChild() bool {
+	load()
+
+	return readyFile != nil
+}
+
+// Take returns the listener handed over under the name, nil when there is none
+func Take(name string) net.Listener {
+	load()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	ln := inherited[name]
+	delete(inherited, name)
+
+	return ln
+}
+
+// Ready tells the parent process that the listeners are served, it can start draining
+func Ready() (err error) {
+	load()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if readyFile == nil {
+		return
+	}
+
+	_, err = readyFile.Write([]byte{1})
+	readyFile.Close()
+	readyFile = nil
+
+	return
+}
+
+// load reads the handed over descriptors once and clears the environment
+// so they are not handed over again by mistake
+func load() {
+	once.Do(func() {
+		inherited = make(map[string]net.Listener)
+
+		readyFD, err := strconv.Atoi(os.Getenv(EnvReadyFD))
+		if err != nil {
+			return
+		}
+
+		names := strings.Split(os.Getenv(EnvListeners), ",")
+		os.Unsetenv(EnvListeners)
+		os.Unsetenv(EnvReadyFD)
+
+		for i, name := range names {
+			if name == "" {
+				continue
+			}
+			f := os.NewFile(uintptr(firstFD+i), name)
+			if ln, err := net.FileListener(f); err == nil {
+				inherited[name] = ln
+			}
+			f.Close()
+		}
+
+		readyFile = os.NewFile(uintptr(readyFD), "ready")
+	})
+}
@@ -0,0 +1,181 @@
+package upgrade
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envChild selects what the test binary does when it is started by Handoff
+const envChild = "UPGRADE_TEST_CHILD"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(envChild); mode != "" {
+		os.Exit(child(mode))
+	}
+
+	os.Exit(m.Run())
+}
+
+// child - runs in the process started by Handoff
+func child(mode string) int {
+	switch mode {
+	case "exit":
+		return 1
+	case "hang":
+		time.Sleep(time.Minute)
+		return 1
+	}
+
+	if !IsChild() {
+		fmt.Fprintln(os.Stderr, "not started by a handoff")
+		return 2
+	}
+	if os.Getenv(EnvListeners) != "" || os.Getenv(EnvReadyFD) != "" {
+		fmt.Fprintln(os.Stderr, "handoff environment not cleared")
+		return 2
+	}
+
+	listeners := make([]net.Listener, 0, 2)
+	for _, name := range []string{"tcp", "unix"} {
+		ln := Take(name)
+		if ln == nil || Take(name) != nil {
+			fmt.Fprintf(os.Stderr, "listener %s not inherited once\n", name)
+			return 2
+		}
+		listeners = append(listeners, ln)
+	}
+
+	if err := Ready(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	for _, ln := range listeners {
+		c, err := ln.Accept()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		fmt.Fprintf(c, "served by %d on %s\n", os.Getpid(), ln.Addr().Network())
+		c.Close()
+	}
+
+	return 0
+}
+
+// handoff - starts the test binary as the new process in the given mode
+func handoff(t *testing.T, mode string, listeners []Listener, timeout time.Duration) (*os.Process, error) {
+	t.Setenv(envChild, mode)
+
+	return Handoff(os.Args[0], []string{"-test.run=^$"}, listeners, timeout)
+}
+
+func TestHandoff(t *testing.T) {
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcp.Close()
+
+	socket := filepath.Join(t.TempDir(), "test.sock")
+	unix, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close()
+
+	tcpFile, err := tcp.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcpFile.Close()
+	unixFile, err := unix.(*net.UnixListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unixFile.Close()
+
+	process, err := handoff(t, "serve", []Listener{{Name: "tcp", File: tcpFile}, {Name: "unix", File: unixFile}}, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// this process no longer accepts, the connections reach the new one through the shared sockets
+	for _, target := range [][2]string{{"tcp", tcp.Addr().String()}, {"unix", socket}} {
+		c, err := net.DialTimeout(target[0], target[1], 5*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetDeadline(time.Now().Add(5 * time.Second))
+		line, err := bufio.NewReader(c).ReadString('\n')
+		c.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", target[0], err)
+		}
+
+		want := fmt.Sprintf("served by %d on %s", process.Pid, target[0])
+		if strings.TrimSpace(line) != want {
+			t.Errorf("expected `%s`, got `%s`", want, strings.TrimSpace(line))
+		}
+	}
+
+	state, err := process.Wait()
+	if err != nil || !state.Success() {
+		t.Fatalf("new process failed: %v %v", state, err)
+	}
+}
+
+func TestHandoffNotReady(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		mode    string
+		timeout time.Duration
+		problem string
+	}{
+		{"exit", 10 * time.Second, "exited before it was ready"},
+		{"hang", 200 * time.Millisecond, "not ready after 200ms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			start := time.Now()
+			process, err := handoff(t, tt.mode, []Listener{{Name: "tcp", File: f}}, tt.timeout)
+			if err == nil || !strings.Contains(err.Error(), tt.problem) {
+				t.Fatalf("expected `%s`, got %v %v", tt.problem, process, err)
+			}
+			// a hanging process is killed instead of waited for
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("handoff returned after %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestNotAChild(t *testing.T) {
+	if IsChild() {
+		t.Fatal("the test process was not started by a handoff")
+	}
+	if ln := Take("tcp"); ln != nil {
+		t.Errorf("unexpected inherited listener %s", ln.Addr())
+	}
+	if err := Ready(); err != nil {
+		t.Errorf("ready without a parent: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Build details, set with
+// -ldflags "-X httpframwork/modules/version.Version=1.2.3 -X httpframwork/modules/version.Commit=abc -X httpframwork/modules/version.BuildTime=2006-01-02T15:04:05Z"
+// Values left empty are read from the build info embedded by the go tool.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	Modified  bool   `json:"modified"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build details, ldflags take precedence over the embedded build info
+func Get() Info {
+	once.Do(func() {
+		info = read(debug.ReadBuildInfo())
+	})
+
+	return info
+}
+
+// read merges the ldflags values with the build info, if the binary embeds one
+func read(bi *debug.BuildInfo, ok bool) Info {
+	i := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+	if !ok {
+		return i
+	}
+
+	if i.Version == "dev" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		i.Version = bi.Main.Version
+	}
+
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if i.Commit == "" {
+				i.Commit = s.Value
+			}
+		case "vcs.time":
+			if i.BuildTime == "" {
+				i.BuildTime = s.Value
+			}
+		case "vcs.modified":
+			i.Modified = s.Value == "true"
+		}
+	}
+
+	return i
+}
+
+// String formats the build details on one line
+func (i Info) String() string {
+	commit := i.Commit
+	if commit == "" {
+		commit = "unknown"
+	} else if i.Modified {
+		commit += "-dirty"
+	}
+
+	buildTime := i.BuildTime
+	if buildTime == "" {
+		buildTime = "unknown"
+	}
+
+	return fmt.Sprintf("version %s, commit %s, built %s with %s", i.Version, commit, buildTime, i.GoVersion)
+}
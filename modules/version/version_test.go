@@ -0,0 +1,81 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	embedded := &debug.BuildInfo{
+		Main: debug.Module{Version: "v1.4.0"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123abc"},
+			{Key: "vcs.time", Value: "2026-01-02T03:04:05Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	tests := []struct {
+		name                       string
+		version, commit, buildTime string
+		bi                         *debug.BuildInfo
+		want                       Info
+	}{
+		{
+			"ldflags win over the build info",
+			"1.2.3", "fedcba9", "2026-05-06T07:08:09Z", embedded,
+			Info{Version: "1.2.3", Commit: "fedcba9", BuildTime: "2026-05-06T07:08:09Z", Modified: true},
+		},
+		{
+			"build info fills the empty values",
+			"dev", "", "", embedded,
+			Info{Version: "v1.4.0", Commit: "0123abc", BuildTime: "2026-01-02T03:04:05Z", Modified: true},
+		},
+		{
+			"partial ldflags",
+			"dev", "fedcba9", "", embedded,
+			Info{Version: "v1.4.0", Commit: "fedcba9", BuildTime: "2026-01-02T03:04:05Z", Modified: true},
+		},
+		{
+			"devel build keeps the default version",
+			"dev", "", "", &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}},
+			Info{Version: "dev"},
+		},
+		{
+			"no build info",
+			"1.2.3", "", "", nil,
+			Info{Version: "1.2.3"},
+		},
+	}
+
+	defer func(version, commit, buildTime string) { Version, Commit, BuildTime = version, commit, buildTime }(Version, Commit, BuildTime)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Version, Commit, BuildTime = tt.version, tt.commit, tt.buildTime
+			tt.want.GoVersion = runtime.Version()
+			if got := read(tt.bi, tt.bi != nil); got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		info Info
+		want string
+	}{
+		{Info{Version: "1.2.3", Commit: "abc", BuildTime: "2026-01-02T03:04:05Z", GoVersion: "go1.24"}, "version 1.2.3, commit abc, built 2026-01-02T03:04:05Z with go1.24"},
+		{Info{Version: "1.2.3", Commit: "abc", Modified: true, GoVersion: "go1.24"}, "version 1.2.3, commit abc-dirty, built unknown with go1.24"},
+		{Info{Version: "dev", Modified: true, GoVersion: "go1.24"}, "version dev, commit unknown, built unknown with go1.24"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.info.String(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}